@@ -1,99 +1,1926 @@
 package ttlcache
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// ErrClosed is returned by the methods that report errors once the cache is closed
+var ErrClosed = errors.New("ttlcache: cache is closed")
+
 // Cache is a synchronised map of items that auto-expire once stale
-type Cache struct {
+//
+// OnEvicted, if set, is invoked for every item that leaves the cache, after
+// the operation that removed it has released the cache mutex, so it may safely
+// call back into the cache. Evictions caused by a single call are reported on
+// the calling goroutine in the order they happened; evictions of a cleanup
+// sweep are reported on the cleanup goroutine once the sweep completes.
+// It must be set before the cache is used.
+//
+// OnExpire, if set, is invoked like OnEvicted but only for the items that
+// outlived their ttl, whether they were swept or removed once expired. Deleted,
+// replaced, evicted and flushed items are not reported. When both are set,
+// OnEvicted is invoked first for every item. It must be set before the cache is used.
+//
+// OnAccess, if set, is invoked with the key of every hit of Get and GetMany,
+// on the calling goroutine once the cache mutex is released. Misses and
+// expired reads are not reported. It must be set before the cache is used.
+//
+// Caches created with a Config.DispatchBuffer invoke OnEvicted and OnAccess on
+// a dedicated goroutine instead, in the order they were triggered, so the
+// callbacks never hold up cache operations.
+//
+// CanEvict, if set, is consulted before an item is evicted to keep the cache
+// within its caps, and pins the item if it returns false. It is called with
+// the cache mutex held, so it must not call back into the cache. When every
+// item is pinned the cache temporarily overflows its caps rather than
+// rejecting new items. It must be set before the cache is used.
+//
+// OnDebug, if set, receives printf-style messages after every cleanup sweep,
+// reporting the number of swept items and of dropped notifications. It is not
+// called on lookups and stores. It must be set before the cache is used.
+//
+// OnSlowCleanup, if set, is invoked after a sweep that held the cache mutex for
+// longer than the threshold set with SetSlowCleanupThreshold, with the time it
+// took and the number of items it started with. It must be set before the cache is used.
+//
+// KeyFunc, if set, normalizes every key passed to the cache, such as by
+// lowercasing or trimming it, so that keys normalizing to the same key share an
+// item. It must be idempotent. Keys reported by the cache, by Keys, Range,
+// GetMany or Evictions for instance, are the normalized ones, and prefixes and
+// patterns of DeletePrefix, DeleteMatch and KeysMatch are matched against the
+// normalized keys as they are given, so they must be written in normalized
+// form. It must be set before the cache is used.
+//
+// TTLFunc, if set, picks the ttl of every item stored with the default ttl from
+// its key and data, such as from the max-age of a cached response. A ttl of 0
+// uses the cache default and a negative ttl never expires, as with SetWithTTL.
+// It is called with the cache mutex held, so it must not call back into the
+// cache. It must be set before the cache is used.
+//
+// Merge, if set, combines the data of Set, SetWithTTL, SetMany and LoadFrom with
+// the data of the live item they replace, such as to append to a log or
+// accumulate a total, instead of the last write winning. The merged item gets a
+// fresh ttl, and an item that is missing or expired is simply stored. It is
+// called with the cache mutex held, so concurrent stores of a key are merged one
+// at a time and it must not call back into the cache. It must be set before the
+// cache is used.
+//
+// OnPanic, if set, receives the value of a panic recovered from a callback run
+// by the cleanup goroutine or the dispatch goroutine, such as OnEvicted for a
+// swept item. Callbacks should not panic, but the cache recovers so that sweeps
+// and dispatching carry on, losing the remaining notifications of the
+// interrupted sweep. Panics are discarded if OnPanic is nil. Callbacks run by
+// other operations, including Cleanup, panic in the caller as usual.
+// It must be set before the cache is used.
+//
+// Observer, if set, is given the wall-clock duration of every Get and Set, to
+// record latencies. Without an observer the operations are not timed.
+// It must be set before the cache is used.
+//
+// Sizer, if set, reports the size in bytes of a value for SetMaxBytes;
+// by default strings and byte slices count their length and other values
+// their shallow size. It must be set before the cache is used.
+//
+// Length is the buffer size of FinishedItems and Evictions, it is read-only
+// after construction.
+//
+// Evictions receives an event for every item that leaves the cache, with its
+// key and the reason. FinishedItems only receives the data of every expired
+// item, it is kept for compatibility. Notifications on both channels are
+// sent without blocking by default: once a channel buffer is full, newer
+// notifications are dropped and counted by DroppedNotifications or
+// DroppedEvents, while the older ones are kept for the consumer.
+// Config.Overflow picks another policy. Buffered notifications keep their data
+// alive until they are received, SetKeysOnlyNotifications avoids that for
+// caches of large values.
+//
+// CleanupDone, if the cache was created with Config.CleanupDone, receives the
+// number of removed items at the end of every sweep, background or not. Like
+// the other channels its buffer holds Length sweeps, any further sweep is not
+// reported until the consumer catches up. It is nil otherwise.
+type Cache[K comparable, V any] struct {
 	mutex         sync.RWMutex
 	ttl           time.Duration
-	items         map[string]*Item
+	items         map[K]*Item[V]
 	Length        int
-	FinishedItems chan string
+	FinishedItems chan V
+	Evictions     chan EvictionEvent[K, V]
+	CleanupDone   <-chan int
+	OnEvicted     func(key K, data V, reason EvictionReason)
+	OnExpire      func(key K, data V)
+	OnAccess      func(key K)
+	CanEvict      func(key K, data V) bool
+	OnDebug       func(format string, args ...interface{})
+	OnSlowCleanup func(duration time.Duration, items int)
+	Sizer         func(data V) int64
+	KeyFunc       func(key K) K
+	Observer      Observer
+	TTLFunc       func(key K, data V) time.Duration
+	Merge         func(existing, incoming V) V
+	OnPanic       func(r interface{})
+	done          chan struct{}
+	closed        bool
+	noSliding     bool
+	strictExpiry  bool
+	maxTTL        time.Duration
+	maxLifetime   time.Duration
+	maxValueSize  int64
+	renewNear     float64
+	jitter        time.Duration
+	slowCleanup   time.Duration
+	version       uint64
+	keysOnly      bool
+	dropped       int64
+	droppedEvents int64
+	callsMutex    sync.Mutex
+	calls         map[K]*call[V]
+	tags          map[string]map[K]struct{}
+	inflight      int64
+	overflow      OverflowPolicy
+	capacity      int
+	stats         stats
+	trackMisses   bool
+	missed        missTracker[K]
+	maxItems      int
+	maxBytes      int64
+	bytes         int64
+	policy        Policy[K]
+	interval      time.Duration
+	minInterval   time.Duration
+	clock         Clock
+	ticker        Ticker
+	cleaning      bool
+	paused        bool
+	cleanupBatch  int
+	lastCleanup   time.Time
+	nextCleanup   time.Time
+
+	expireAfterWrite  time.Duration
+	expireAfterAccess time.Duration
+	loader            func(ctx context.Context, key K) (V, time.Duration, error)
+	fallback          Getter[K, V]
+
+	refreshThreshold time.Duration
+	refreshLoader    func(key K) (V, error)
+	refreshMutex     sync.Mutex
+	refreshing       map[K]struct{}
+	maxRefreshes     int
+	skippedRefreshes int64
+
+	negativeTTL time.Duration
+	errorTTL    time.Duration
+	negatives   map[K]negative
+	waiters     map[K][]chan struct{}
+	arrivals    map[K][]chan struct{}
+	queue       expiryQueue[K, V]
+	copyOnStore bool
+	copyOnGet   bool
+
+	callbacks        chan func()
+	dispatched       bool
+	droppedCallbacks int64
+
+	channelsMutex  sync.RWMutex
+	channelsClosed bool
+	cleanupDone    chan int
 }
 
 // Set is a thread-safe way to add new items to the map
-func (cache *Cache) Set(key string, data string) {
+// Once the cache is closed, Set is a no-op
+func (cache *Cache[K, V]) Set(key K, data V) {
+	cache.SetWithTTL(key, data, 0)
+}
+
+// SetWithTTL is a thread-safe way to add new items to the map with their own ttl
+// A ttl of 0 uses the cache default, a negative ttl never expires
+func (cache *Cache[K, V]) SetWithTTL(key K, data V, ttl time.Duration) {
+	if cache.Observer != nil {
+		start := time.Now()
+		defer func() { cache.Observer.ObserveSet(time.Since(start)) }()
+	}
+	key = cache.normalize(key)
 	cache.mutex.Lock()
-	item := &Item{data: data}
-	item.touch(cache.ttl)
-	cache.items[key] = item
+	if cache.closed {
+		cache.mutex.Unlock()
+		return
+	}
+	evictions := cache.set(key, cache.merged(key, data), ttl)
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+}
+
+// merged returns data combined with the live item of key by Merge, if any,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) merged(key K, data V) V {
+	if cache.Merge == nil {
+		return data
+	}
+	if item, exists := cache.items[key]; exists && !cache.expired(item) {
+		return cache.Merge(item.data, data)
+	}
+	return data
+}
+
+// SetWithDeadline is a thread-safe way to add an item that expires at deadline,
+// which lookups do not extend. A deadline that has already passed stores the
+// item as expired: lookups miss it and the next sweep reports it as Expired
+// A zero deadline never expires, following the rules of a negative ttl
+func (cache *Cache[K, V]) SetWithDeadline(key K, data V, deadline time.Time) {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return
+	}
+	item := cache.newItem(data, -1)
+	item.pin(deadline)
+	evictions := cache.insert(key, item)
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+}
+
+// SetPermanent is a thread-safe way to add an item that never expires
+// Unlike an item set with a negative ttl, it is also exempt from SetMaxTTL,
+// SetExpireAfterWrite and the ttl jitter, so it is never swept. It still counts
+// towards the limits of the cache and can be deleted or evicted. Storing the key
+// again with another method makes the item follow the rules of that method
+func (cache *Cache[K, V]) SetPermanent(key K, data V) {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return
+	}
+	evictions := cache.insert(key, &Item[V]{data: data, ttl: -1, created: cache.now()})
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+}
+
+// SetMany is a thread-safe way to add several items to the map under a single lock
+func (cache *Cache[K, V]) SetMany(items map[K]V) {
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return
+	}
+	var evictions []EvictionEvent[K, V]
+	for key, data := range items {
+		key = cache.normalize(key)
+		evictions = append(evictions, cache.set(key, cache.merged(key, data), 0)...)
+	}
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+}
+
+// GetAndSet is a thread-safe way to store an item, returning the data it replaced
+// existed is false if there was no live item for the key
+func (cache *Cache[K, V]) GetAndSet(key K, data V) (old V, existed bool) {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return
+	}
+	if item, exists := cache.items[key]; exists && !cache.expired(item) {
+		old, existed = cache.loaned(item.data), true
+	}
+	evictions := cache.set(key, data, 0)
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return
+}
+
+// Add is a thread-safe way to store an item only if no live item exists for its key
+// It returns whether the item was stored
+func (cache *Cache[K, V]) Add(key K, data V) bool {
+	return cache.AddWithTTL(key, data, 0)
+}
+
+// AddWithTTL is a thread-safe way to store an item with its own ttl only if
+// no live item exists for its key, like Add. It returns whether the item was stored
+// The ttl follows the rules of SetWithTTL, which makes it usable as a lease
+func (cache *Cache[K, V]) AddWithTTL(key K, data V, ttl time.Duration) bool {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	if item, exists := cache.items[key]; cache.closed || (exists && !cache.expired(item)) {
+		cache.mutex.Unlock()
+		return false
+	}
+	evictions := cache.set(key, data, ttl)
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return true
+}
+
+// Replace is a thread-safe way to update an item only if it is live
+// It refreshes the life of the item, keeping its tags, and returns whether it was updated
+func (cache *Cache[K, V]) Replace(key K, data V) bool {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	item, exists := cache.items[key]
+	if cache.closed || !exists || cache.expired(item) {
+		cache.mutex.Unlock()
+		return false
+	}
+	evictions := cache.insert(key, cache.renewed(key, item, data))
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return true
+}
+
+// Update is a thread-safe way to change the data of a live item without extending
+// its life, unlike Replace it keeps the deadline of the item as it is
+// It returns whether the item was updated, missing and expired items are not
+func (cache *Cache[K, V]) Update(key K, data V) bool {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	item, exists := cache.items[key]
+	if cache.closed || !exists || cache.expired(item) {
+		cache.mutex.Unlock()
+		return false
+	}
+	evictions := cache.insert(key, item.updated(data))
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return true
+}
+
+// Transform is a thread-safe way to rewrite every live item at once, such as to
+// re-encrypt the values after a key rotation. f receives every item and returns
+// its new data, which keeps the deadline of the item as with Update, or false to
+// delete it. Rewritten items are reported as Replaced and deleted ones as Deleted,
+// while the eviction policy keeps its order of the items as if they were untouched
+// Transform holds the write lock during the whole walk, so f must not call back
+// into the cache. It returns the number of deleted items
+func (cache *Cache[K, V]) Transform(f func(key K, data V) (V, bool)) int {
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return 0
+	}
+	// storing the new items while ranging over the map could visit them again
+	keys := make([]K, 0, len(cache.items))
+	for key := range cache.items {
+		keys = append(keys, key)
+	}
+	var evictions []EvictionEvent[K, V]
+	deleted := 0
+	for _, key := range keys {
+		item, exists := cache.items[key]
+		if !exists || cache.expired(item) {
+			continue
+		}
+		data, keep := f(key, cache.loaned(item.data))
+		if keep {
+			var stored bool
+			if evictions, stored = cache.rewrite(evictions, key, item, data); stored {
+				continue
+			}
+		}
+		evictions = cache.remove(evictions, key, item, Deleted)
+		deleted++
+	}
+	evictions = cache.evictOverflow(evictions)
+	cache.stats.grown(len(cache.items), cache.bytes)
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return deleted
+}
+
+// CompareAndSwap is a thread-safe way to update a live item only if its data equals old,
+// as reported by reflect.DeepEqual. It refreshes the life of the item and returns
+// whether it was updated, missing and expired items are never swapped
+func (cache *Cache[K, V]) CompareAndSwap(key K, old, data V) bool {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	item, exists := cache.items[key]
+	if cache.closed || !exists || cache.expired(item) || !reflect.DeepEqual(item.data, old) {
+		cache.mutex.Unlock()
+		return false
+	}
+	evictions := cache.insert(key, cache.renewed(key, item, data))
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return true
+}
+
+// GetVersioned is a thread-safe way to lookup items along with their version,
+// without extending their life. Every write of an item gives it a new version,
+// larger than any version before it, so two reads returning the same version
+// saw the same write, even if the key was deleted and set again in between
+func (cache *Cache[K, V]) GetVersioned(key K) (data V, version uint64, found bool) {
+	key = cache.normalize(key)
+	cache.mutex.RLock()
+	item, exists := cache.items[key]
+	if !cache.closed && exists && !cache.expired(item) {
+		data, version, found = cache.loaned(item.data), item.version, true
+	}
+	cache.mutex.RUnlock()
+	return
+}
+
+// CompareVersionAndSwap is a thread-safe way to update a live item only if it is
+// still at version, as returned by GetVersioned. It refreshes the life of the
+// item and returns whether it was updated
+func (cache *Cache[K, V]) CompareVersionAndSwap(key K, version uint64, data V) bool {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	item, exists := cache.items[key]
+	if cache.closed || !exists || cache.expired(item) || item.version != version {
+		cache.mutex.Unlock()
+		return false
+	}
+	evictions := cache.insert(key, cache.renewed(key, item, data))
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return true
+}
+
+// GetOrSet is a thread-safe way to lookup an item, storing data if it is missing
+// It returns the existing data and true, or the stored data and false
+func (cache *Cache[K, V]) GetOrSet(key K, data V) (actual V, loaded bool) {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return
+	}
+	if item, exists := cache.items[key]; exists && !cache.expired(item) {
+		// account for the hit as lookupItem does
+		refresh := cache.needsRefresh(item)
+		if !cache.noSliding {
+			cache.renew(item)
+		}
+		cache.promote(key)
+		item.accessed()
+		actual = cache.loaned(item.data)
+		loader := cache.refreshLoader
+		onAccess := cache.OnAccess
+		cache.mutex.Unlock()
+		cache.stats.lookup(true)
+		if onAccess != nil {
+			cache.dispatch(func() { onAccess(key) })
+		}
+		if refresh {
+			cache.refresh(key, item, loader)
+		}
+		return actual, true
+	}
+	tracking := cache.trackMisses
+	evictions := cache.set(key, data, 0)
+	onEvicted := cache.OnEvicted
 	cache.mutex.Unlock()
+	cache.stats.lookup(false)
+	if tracking {
+		cache.missed.miss(key)
+	}
+	cache.notify(onEvicted, evictions)
+	return data, false
+}
+
+// normalize applies KeyFunc to key
+func (cache *Cache[K, V]) normalize(key K) K {
+	if cache.KeyFunc != nil {
+		return cache.KeyFunc(key)
+	}
+	return key
+}
+
+// set stores a new item, it must be called with the cache mutex held
+func (cache *Cache[K, V]) set(key K, data V, ttl time.Duration) []EvictionEvent[K, V] {
+	return cache.insert(key, cache.newItem(data, cache.ttlFor(key, data, ttl)))
+}
+
+// renewed returns a new item holding data to update the live item existing, which
+// starts a new life with the ttl of existing but keeps its fixed deadline, tags
+// and access count, it must be called with the cache mutex held
+func (cache *Cache[K, V]) renewed(key K, existing *Item[V], data V) *Item[V] {
+	item := cache.newItem(data, cache.ttlFor(key, data, existing.ttl))
+	item.pin(existing.fixed)
+	item.tags = existing.tags
+	item.accesses = atomic.LoadUint64(&existing.accesses)
+	return item
+}
+
+// ttlFor returns the ttl given by TTLFunc for an item stored with the default ttl
+func (cache *Cache[K, V]) ttlFor(key K, data V, ttl time.Duration) time.Duration {
+	if ttl == 0 && cache.TTLFunc != nil {
+		return cache.TTLFunc(key, data)
+	}
+	return ttl
+}
+
+// newItem returns an item whose deadline starts now,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) newItem(data V, ttl time.Duration) *Item[V] {
+	if ttl == 0 && cache.ttl < 0 {
+		ttl = -1
+	}
+	if cache.maxTTL > 0 && (ttl < 0 || ttl > cache.maxTTL) {
+		ttl = cache.maxTTL
+	}
+	item := &Item[V]{data: data, ttl: ttl, created: cache.now()}
+	if cache.jitter > 0 {
+		item.jitter = time.Duration(rand.Int63n(int64(cache.jitter)))
+	}
+	if cache.expireAfterWrite > 0 {
+		item.written = item.created.Add(cache.expireAfterWrite)
+	}
+	cache.touch(item)
+	if cache.strictExpiry && ttl >= 0 {
+		// pin the first deadline, which touches then cannot move
+		if first := *item.expires; item.written.IsZero() || first.Before(item.written) {
+			item.written = first
+		}
+	}
+	return item
+}
+
+// insert stores an item whose deadline is already set, sizing it by its weight
+// or else by its data. An item too large to be stored leaves its key without
+// an item, reporting the one it would have replaced as Deleted,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) insert(key K, item *Item[V]) (evictions []EvictionEvent[K, V]) {
+	if cache.copyOnStore {
+		item.data = cloneValue(item.data)
+	}
+	rejected := cache.measure(item)
+	if existing, exists := cache.items[key]; exists {
+		reason := Replaced
+		if rejected {
+			reason = Deleted
+		}
+		evictions = cache.remove(evictions, key, existing, reason)
+	}
+	delete(cache.negatives, key)
+	if rejected {
+		return
+	}
+	cache.version++
+	item.version = cache.version
+	cache.items[key] = item
+	cache.bytes += item.size
+	cache.link(key)
+	cache.schedule(key, item)
+	cache.tag(key, item)
+	cache.arrive(key)
+	evictions = cache.evictOverflow(evictions)
+	cache.stats.grown(len(cache.items), cache.bytes)
+	return evictions
+}
+
+// measure sizes an item by its weight or else by its data, and reports whether
+// it is too large to be stored, it must be called with the cache mutex held
+func (cache *Cache[K, V]) measure(item *Item[V]) bool {
+	item.size = item.weight
+	if item.size <= 0 {
+		item.size = cache.sizeOf(item.data)
+	}
+	return (cache.maxBytes > 0 && item.size > cache.maxBytes) || cache.tooLarge(item.data)
+}
+
+// rewrite swaps the data of a stored item for data in a copy of the item, which
+// keeps its deadline and its place in the eviction policy, and reports the
+// former item as Replaced. It returns false, leaving the item as it is, if data
+// is too large to be stored, it must be called with the cache mutex held
+func (cache *Cache[K, V]) rewrite(evictions []EvictionEvent[K, V], key K, item *Item[V], data V) ([]EvictionEvent[K, V], bool) {
+	updated := item.updated(data)
+	if cache.copyOnStore {
+		updated.data = cloneValue(updated.data)
+	}
+	if cache.measure(updated) {
+		return evictions, false
+	}
+	cache.unschedule(item)
+	cache.version++
+	updated.version = cache.version
+	cache.items[key] = updated
+	cache.bytes += updated.size - item.size
+	cache.schedule(key, updated)
+	return evict(evictions, key, item, Replaced, cache.now()), true
+}
+
+// remove deletes an item from the map and records its eviction,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) remove(evictions []EvictionEvent[K, V], key K, item *Item[V], reason EvictionReason) []EvictionEvent[K, V] {
+	delete(cache.items, key)
+	cache.bytes -= item.size
+	cache.unlink(key)
+	cache.unschedule(item)
+	cache.untag(key, item)
+	if reason != Replaced {
+		cache.release(key)
+	}
+	return evict(evictions, key, item, reason, cache.now())
 }
 
 // Get is a thread-safe way to lookup items
-// Every lookup, also touches the item, hence extending it's life
-func (cache *Cache) Get(key string) (data string, found bool) {
+// Every lookup, also touches the item, hence extending it's life,
+// unless sliding expiration has been disabled
+// Misses are loaded by the loader registered with SetLoader, if any
+// Once the cache is closed, every lookup reports not found
+func (cache *Cache[K, V]) Get(key K) (data V, found bool) {
+	if cache.Observer != nil {
+		start := time.Now()
+		defer func() { cache.Observer.ObserveGet(found, time.Since(start)) }()
+	}
+	key = cache.normalize(key)
+	if data, found = cache.lookup(key); found {
+		return
+	}
+	if data, found = cache.fallBack(key); found {
+		return
+	}
+	if loader := cache.registered(); loader != nil {
+		var err error
+		data, err = cache.compute(context.Background(), key, loader)
+		found = err == nil
+	}
+	return
+}
+
+// GetOrReap is like Get, but removes an expired item for key right away instead
+// of leaving it to the next sweep, reporting it as if it had been swept
+func (cache *Cache[K, V]) GetOrReap(key K) (data V, found bool) {
+	key = cache.normalize(key)
 	cache.mutex.Lock()
+	var evictions []EvictionEvent[K, V]
+	if item, exists := cache.items[key]; exists && !cache.closed && cache.expired(item) {
+		evictions = cache.remove(evictions, key, item, Expired)
+	}
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.finish(evictions, cache.done)
+	cache.notify(onEvicted, evictions)
+	return cache.Get(key)
+}
+
+// GetEntry is a thread-safe way to lookup an item along with its metadata under a
+// single lock acquisition. Like Get it touches the item unless sliding expiration
+// has been disabled, and the deadline and access count are those after the lookup
+// Unlike Get, misses are not looked up in the fallback tier nor loaded
+func (cache *Cache[K, V]) GetEntry(key K) (info ItemInfo[V], found bool) {
+	found = cache.lookupItem(cache.normalize(key), func(item *Item[V]) {
+		info = item.info(cache.loaned(item.data))
+	})
+	return
+}
+
+// lookup is Get without loading misses
+func (cache *Cache[K, V]) lookup(key K) (data V, found bool) {
+	found = cache.lookupItem(key, func(item *Item[V]) {
+		data = cache.loaned(item.data)
+	})
+	return
+}
+
+// lookupItem looks up a live item like Get, calling hit with the cache mutex
+// held once the item is touched, and reports whether it was found
+func (cache *Cache[K, V]) lookupItem(key K, hit func(item *Item[V])) (found bool) {
+	var refresh bool
+	exclusive := cache.lockForRead()
 	item, exists := cache.items[key]
-	if !exists || item.expired() {
-		data = ""
+	tracking := cache.trackMisses && !cache.closed
+	if cache.closed || !exists || cache.expired(item) {
 		found = false
 	} else {
-		item.touch(cache.ttl)
-		data = item.data
+		refresh = cache.needsRefresh(item)
+		if !cache.noSliding {
+			cache.renew(item)
+		}
+		if exclusive {
+			cache.promote(key)
+		}
+		item.accessed()
+		hit(item)
 		found = true
 	}
+	loader := cache.refreshLoader
+	onAccess := cache.OnAccess
+	cache.unlockForRead(exclusive)
+	cache.stats.lookup(found)
+	if !found && tracking {
+		cache.missed.miss(key)
+	}
+	if found && onAccess != nil {
+		cache.dispatch(func() { onAccess(key) })
+	}
+	if refresh {
+		cache.refresh(key, item, loader)
+	}
+	return
+}
+
+// GetMany is a thread-safe way to lookup several items under a single lock acquisition
+// Only the keys of live items are present in the result, and like Get
+// every lookup touches the item unless sliding expiration has been disabled
+func (cache *Cache[K, V]) GetMany(keys []K) map[K]V {
+	result := make(map[K]V, len(keys))
+	cache.getMany(keys, func(key K, item *Item[V]) {
+		result[key] = cache.loaned(item.data)
+	})
+	return result
+}
+
+// GetManyWithExpiration is like GetMany, but also returns the deadline of every
+// live item, as it stands after the lookup, and its access count
+// Items that never expire report the zero time
+func (cache *Cache[K, V]) GetManyWithExpiration(keys []K) map[K]ItemInfo[V] {
+	result := make(map[K]ItemInfo[V], len(keys))
+	cache.getMany(keys, func(key K, item *Item[V]) {
+		result[key] = item.info(cache.loaned(item.data))
+	})
+	return result
+}
+
+// getMany looks up several items under a single lock acquisition, calling hit
+// with the cache mutex held for every live item once it has been touched
+func (cache *Cache[K, V]) getMany(keys []K, hit func(key K, item *Item[V])) {
+	var hits, misses []K
+	exclusive := cache.lockForRead()
+	tracking := cache.trackMisses
+	if !cache.closed {
+		for _, key := range keys {
+			key = cache.normalize(key)
+			item, exists := cache.items[key]
+			if !exists || cache.expired(item) {
+				if tracking {
+					misses = append(misses, key)
+				}
+				continue
+			}
+			if !cache.noSliding {
+				cache.renew(item)
+			}
+			if exclusive {
+				cache.promote(key)
+			}
+			item.accessed()
+			hit(key, item)
+			hits = append(hits, key)
+		}
+	}
+	onAccess := cache.OnAccess
+	cache.unlockForRead(exclusive)
+	for range keys[len(hits):] {
+		cache.stats.lookup(false)
+	}
+	for _, key := range misses {
+		cache.missed.miss(key)
+	}
+	for _, key := range hits {
+		cache.stats.lookup(true)
+		if onAccess != nil {
+			key := key
+			cache.dispatch(func() { onAccess(key) })
+		}
+	}
+}
+
+// Touch is a thread-safe way to extend the life of a live item without reading it
+// It returns whether the item was live, missing and expired items are not resurrected
+func (cache *Cache[K, V]) Touch(key K) bool {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	item, exists := cache.items[key]
+	live := !cache.closed && exists && !cache.expired(item)
+	if live {
+		cache.touch(item)
+		cache.promote(key)
+	}
 	cache.mutex.Unlock()
+	return live
+}
+
+// TouchMany is a thread-safe way to extend the life of several live items under a
+// single lock acquisition, like Touch. It returns the number of items extended,
+// missing and expired items are skipped and not resurrected
+func (cache *Cache[K, V]) TouchMany(keys []K) int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.closed {
+		return 0
+	}
+	touched := 0
+	for _, key := range keys {
+		key = cache.normalize(key)
+		if item, exists := cache.items[key]; exists && !cache.expired(item) {
+			cache.touch(item)
+			cache.promote(key)
+			touched++
+		}
+	}
+	return touched
+}
+
+// Peek is a thread-safe way to lookup items without extending their life
+func (cache *Cache[K, V]) Peek(key K) (data V, found bool) {
+	key = cache.normalize(key)
+	cache.mutex.RLock()
+	item, exists := cache.items[key]
+	if !cache.closed && exists && !cache.expired(item) {
+		data = cache.loaned(item.data)
+		found = true
+	}
+	cache.mutex.RUnlock()
+	return
+}
+
+// Has reports whether a live item exists for key, without extending its life
+func (cache *Cache[K, V]) Has(key K) bool {
+	key = cache.normalize(key)
+	cache.mutex.RLock()
+	item, exists := cache.items[key]
+	live := !cache.closed && exists && !cache.expired(item)
+	cache.mutex.RUnlock()
+	return live
+}
+
+// GetWithExpiration is a thread-safe way to lookup items along with their deadline,
+// without extending their life
+// Items that never expire report the zero time
+func (cache *Cache[K, V]) GetWithExpiration(key K) (data V, expiresAt time.Time, found bool) {
+	key = cache.normalize(key)
+	cache.mutex.RLock()
+	item, exists := cache.items[key]
+	if !cache.closed && exists && !cache.expired(item) {
+		data = cache.loaned(item.data)
+		expiresAt = item.deadline()
+		found = true
+	}
+	cache.mutex.RUnlock()
 	return
 }
 
+// TTLRemaining returns how long until an item expires, without extending its life
+// Items that never expire report a negative duration
+func (cache *Cache[K, V]) TTLRemaining(key K) (time.Duration, bool) {
+	_, expiresAt, found := cache.GetWithExpiration(key)
+	if !found {
+		return 0, false
+	}
+	if expiresAt.IsZero() {
+		return -1, true
+	}
+	return expiresAt.Sub(cache.now()), true
+}
+
+// ExpiresAt returns the deadline of a live item without extending its life
+// Items that never expire report the zero time
+func (cache *Cache[K, V]) ExpiresAt(key K) (time.Time, bool) {
+	key = cache.normalize(key)
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	item, exists := cache.items[key]
+	if cache.closed || !exists || cache.expired(item) {
+		return time.Time{}, false
+	}
+	return item.deadline(), true
+}
+
+// ExpiringBefore returns the keys of the live items that expire before deadline,
+// in no particular order and without extending their life
+// Items that never expire are never reported
+func (cache *Cache[K, V]) ExpiringBefore(deadline time.Time) []K {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	var keys []K
+	for key, item := range cache.items {
+		if cache.expired(item) {
+			continue
+		}
+		if expiresAt := item.deadline(); !expiresAt.IsZero() && expiresAt.Before(deadline) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
 // Delete is a thread-safe way to delete an item
-func (cache *Cache) Delete(key string) {
+func (cache *Cache[K, V]) Delete(key K) {
+	key = cache.normalize(key)
 	cache.mutex.Lock()
-	delete(cache.items, key)
+	var evictions []EvictionEvent[K, V]
+	if item, exists := cache.items[key]; exists {
+		evictions = cache.remove(evictions, key, item, Deleted)
+	}
+	onEvicted := cache.OnEvicted
 	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
 }
 
-// Count returns the number of items in the cache
-// (helpful for tracking memory leaks)
-func (cache *Cache) Count() int {
+// Range calls f for every live item in the cache, stopping early if f returns false
+// Range holds the read lock during the whole iteration, so f must not call back
+// into the cache. Range does not extend the life of the items it visits
+func (cache *Cache[K, V]) Range(f func(key K, data V) bool) {
 	cache.mutex.RLock()
-	count := len(cache.items)
+	defer cache.mutex.RUnlock()
+	for key, item := range cache.items {
+		if cache.expired(item) {
+			continue
+		}
+		if !f(key, cache.loaned(item.data)) {
+			return
+		}
+	}
+}
+
+// Snapshot returns a copy of all live items, which callers may iterate and modify
+// without holding any lock. It allocates a map the size of the cache on every call
+// Like Range, Snapshot does not extend the life of the items it copies
+func (cache *Cache[K, V]) Snapshot() map[K]V {
+	cache.mutex.RLock()
+	snapshot := make(map[K]V, len(cache.items))
+	for key, item := range cache.items {
+		if !cache.expired(item) {
+			snapshot[key] = cache.loaned(item.data)
+		}
+	}
 	cache.mutex.RUnlock()
-	return count
+	return snapshot
+}
+
+// Entries returns a copy of all live items along with their deadline and the number
+// of lookups that found them. Byte slice data is copied, so the result shares
+// nothing with the cache. Like Range, Entries does not extend the life of the items
+func (cache *Cache[K, V]) Entries() map[K]ItemInfo[V] {
+	cache.mutex.RLock()
+	entries := make(map[K]ItemInfo[V], len(cache.items))
+	for key, item := range cache.items {
+		if !cache.expired(item) {
+			entries[key] = item.info(cloneValue(item.data))
+		}
+	}
+	cache.mutex.RUnlock()
+	return entries
 }
 
-func (cache *Cache) cleanup() {
+// DeletePrefix is a thread-safe way to delete every item whose key starts with prefix
+// It returns the number of deleted items, keys that are not strings never match
+func (cache *Cache[K, V]) DeletePrefix(prefix string) int {
 	cache.mutex.Lock()
+	var evictions []EvictionEvent[K, V]
 	for key, item := range cache.items {
-		if item.expired() {
-			delete(cache.items, key)
-			if len(cache.FinishedItems) == cache.Length {
-				<-cache.FinishedItems
-			}
-			cache.FinishedItems <- item.data
+		if s, ok := keyString(key); ok && strings.HasPrefix(s, prefix) {
+			evictions = cache.remove(evictions, key, item, Deleted)
 		}
 	}
+	onEvicted := cache.OnEvicted
 	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return len(evictions)
 }
 
-func (cache *Cache) startCleanupTimer() {
-	duration := cache.ttl
-	if duration < time.Second {
-		duration = time.Second
+// DeleteMatch is a thread-safe way to delete all items whose key matches
+// the glob pattern, returning the number of items deleted
+// Patterns follow filepath.Match, so `*` does not match the path separator
+// A malformed pattern returns filepath.ErrBadPattern without deleting anything,
+// keys that are not strings never match
+func (cache *Cache[K, V]) DeleteMatch(pattern string) (int, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return 0, err
 	}
-	ticker := time.Tick(duration)
-	go (func() {
-		for {
-			select {
-			case <-ticker:
-				cache.cleanup()
-			}
+	cache.mutex.Lock()
+	var evictions []EvictionEvent[K, V]
+	for key, item := range cache.items {
+		if cache.matches(pattern, key) {
+			evictions = cache.remove(evictions, key, item, Deleted)
 		}
-	})()
+	}
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return len(evictions), nil
+}
+
+// matches reports whether key is a string matching the glob pattern
+func (cache *Cache[K, V]) matches(pattern string, key K) bool {
+	s, ok := keyString(key)
+	if !ok {
+		return false
+	}
+	matched, _ := filepath.Match(pattern, s)
+	return matched
 }
 
-// NewCache is a helper to create instance of the Cache struct
-func NewCache(duration time.Duration) *Cache {
-	cache := &Cache{
-		ttl:    duration,
-		items:  map[string]*Item{},
-		Length: 10,
+// keyString returns key as a string if its underlying type is string
+func keyString[K comparable](key K) (string, bool) {
+	if s, ok := any(key).(string); ok {
+		return s, true
+	}
+	if value := reflect.ValueOf(key); value.Kind() == reflect.String {
+		return value.String(), true
+	}
+	return "", false
+}
+
+// Flush is a thread-safe way to delete all items at once
+// Every removed item is reported with the Flushed reason to OnEvicted and on
+// Evictions, and counted in Stats, but nothing is sent on FinishedItems
+func (cache *Cache[K, V]) Flush() {
+	cache.mutex.Lock()
+	var evictions []EvictionEvent[K, V]
+	now := cache.now()
+	for key, item := range cache.items {
+		evictions = evict(evictions, key, item, Flushed, now)
+	}
+	cache.dropAll()
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+}
+
+// dropAll removes every item without reporting them,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) dropAll() {
+	cache.releaseAll()
+	cache.negatives = nil
+	cache.tags = nil
+	cache.bytes = 0
+	if cache.policy != nil {
+		for key := range cache.items {
+			cache.policy.Removed(key)
+		}
+	}
+	cache.items = make(map[K]*Item[V], cache.capacity)
+	cache.queue = nil
+}
+
+// Keys returns a snapshot of the keys of all live items in the cache
+func (cache *Cache[K, V]) Keys() []K {
+	cache.mutex.RLock()
+	keys := make([]K, 0, len(cache.items))
+	for key, item := range cache.items {
+		if !cache.expired(item) {
+			keys = append(keys, key)
+		}
+	}
+	cache.mutex.RUnlock()
+	return keys
+}
+
+// SortedKeys returns a snapshot of the keys of all live items in lexical order,
+// keys that are not strings being ordered by their fmt representation
+// Unlike Keys, it sorts the keys on every call, which costs O(n log n) and an
+// extra allocation per key that is not a string
+func (cache *Cache[K, V]) SortedKeys() []K {
+	keys := cache.Keys()
+	sortKeys(keys)
+	return keys
+}
+
+// RangeSorted is like Range, but visits the live items in the order of SortedKeys
+// It collects and sorts the keys before the first call of f, holding the read lock
+// throughout like Range
+func (cache *Cache[K, V]) RangeSorted(f func(key K, data V) bool) {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	keys := make([]K, 0, len(cache.items))
+	for key, item := range cache.items {
+		if !cache.expired(item) {
+			keys = append(keys, key)
+		}
+	}
+	sortKeys(keys)
+	for _, key := range keys {
+		if !f(key, cache.loaned(cache.items[key].data)) {
+			return
+		}
+	}
+}
+
+// keysByName sorts keys by their string form
+type keysByName[K comparable] struct {
+	keys  []K
+	names []string
+}
+
+func (keys keysByName[K]) Len() int           { return len(keys.keys) }
+func (keys keysByName[K]) Less(i, j int) bool { return keys.names[i] < keys.names[j] }
+func (keys keysByName[K]) Swap(i, j int) {
+	keys.keys[i], keys.keys[j] = keys.keys[j], keys.keys[i]
+	keys.names[i], keys.names[j] = keys.names[j], keys.names[i]
+}
+
+// sortKeys sorts keys in lexical order of their string form
+func sortKeys[K comparable](keys []K) {
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		if s, ok := keyString(key); ok {
+			names[i] = s
+		} else {
+			names[i] = fmt.Sprint(key)
+		}
+	}
+	sort.Sort(keysByName[K]{keys: keys, names: names})
+}
+
+// KeysMatch returns the keys of all live items matching the glob pattern, in no particular order
+// Patterns follow the same rules as DeleteMatch. A closed cache returns ErrClosed
+func (cache *Cache[K, V]) KeysMatch(pattern string) ([]K, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	cache.mutex.RLock()
+	if cache.closed {
+		cache.mutex.RUnlock()
+		return nil, ErrClosed
+	}
+	var keys []K
+	for key, item := range cache.items {
+		if cache.matches(pattern, key) && !cache.expired(item) {
+			keys = append(keys, key)
+		}
+	}
+	cache.mutex.RUnlock()
+	return keys, nil
+}
+
+// GetAndDelete is a thread-safe way to delete an item, returning its data
+// existed is false if there was no live item for the key
+func (cache *Cache[K, V]) GetAndDelete(key K) (old V, existed bool) {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	var evictions []EvictionEvent[K, V]
+	if item, exists := cache.items[key]; exists {
+		if !cache.closed && !cache.expired(item) {
+			old, existed = cache.loaned(item.data), true
+		}
+		evictions = cache.remove(evictions, key, item, Deleted)
+	}
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return
+}
+
+// Rename is a thread-safe way to move a live item to another key, keeping its data
+// and deadline. An item already stored under newKey is replaced. It returns
+// false if oldKey has no live item, in which case nothing changes
+func (cache *Cache[K, V]) Rename(oldKey, newKey K) bool {
+	oldKey, newKey = cache.normalize(oldKey), cache.normalize(newKey)
+	cache.mutex.Lock()
+	item, exists := cache.items[oldKey]
+	if cache.closed || !exists || cache.expired(item) {
+		cache.mutex.Unlock()
+		return false
+	}
+	if oldKey == newKey {
+		cache.mutex.Unlock()
+		return true
+	}
+	// the item moves rather than leaves, so its removal is not reported, and
+	// it is stored as a copy since stored items never change
+	cache.remove(nil, oldKey, item, Deleted)
+	evictions := cache.insert(newKey, item.updated(item.data))
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return true
+}
+
+// Count returns the number of items in the cache
+// (helpful for tracking memory leaks)
+// It includes expired items that have not been swept yet, see CountLive
+func (cache *Cache[K, V]) Count() int {
+	cache.mutex.RLock()
+	count := len(cache.items)
+	cache.mutex.RUnlock()
+	return count
+}
+
+// CountLive returns the number of items that have not expired
+// Unlike Count it walks every item, so it is slower for large caches
+func (cache *Cache[K, V]) CountLive() int {
+	cache.mutex.RLock()
+	count := 0
+	for _, item := range cache.items {
+		if !cache.expired(item) {
+			count++
+		}
+	}
+	cache.mutex.RUnlock()
+	return count
+}
+
+// TTL returns the default lifetime of items
+func (cache *Cache[K, V]) TTL() time.Duration {
+	cache.mutex.RLock()
+	ttl := cache.ttl
+	cache.mutex.RUnlock()
+	return ttl
+}
+
+// SetTTL changes the default lifetime of items, a negative ttl stores them
+// without expiry as with SetWithTTL
+// Existing deadlines are left as they are, the new ttl applies from the next
+// time an item is set or touched. The cleanup interval follows the new ttl
+// unless it was set explicitly
+func (cache *Cache[K, V]) SetTTL(ttl time.Duration) {
+	cache.mutex.Lock()
+	if ttl >= 0 && (ttl < cache.ttl || cache.ttl < 0) {
+		cache.expireBefore(cache.now().Add(ttl))
+	}
+	cache.ttl = ttl
+	cache.reschedule()
+	cache.mutex.Unlock()
+}
+
+// SetMaxTTL sets a ceiling on the lifetime of items, longer ttls are clamped to it
+// The ceiling wins over requests to never expire, which then live for max
+// Existing items are clamped the next time they are touched, or set if they never
+// expire. A max of 0 means no ceiling, which is the default
+func (cache *Cache[K, V]) SetMaxTTL(max time.Duration) {
+	cache.mutex.Lock()
+	if max > 0 && (cache.maxTTL <= 0 || max < cache.maxTTL) {
+		cache.expireBefore(cache.now().Add(max))
+	}
+	cache.maxTTL = max
+	cache.mutex.Unlock()
+}
+
+// TouchStrategy decides when lookups renew the life of an item, see SetTouchStrategy
+type TouchStrategy struct {
+	near float64
+}
+
+// RenewAlways renews the life of an item on every lookup, which is the default
+var RenewAlways = TouchStrategy{}
+
+// RenewWhenNear only renews the life of an item on lookups once less than
+// fraction of its ttl is left, such as 0.5 for the second half of its life
+func RenewWhenNear(fraction float64) TouchStrategy {
+	return TouchStrategy{near: fraction}
+}
+
+// SetTouchStrategy sets when lookups renew the life of the items they find
+// Renewing less often saves the write of a deadline on most lookups of hot
+// items, at the cost of items expiring up to the skipped part of their ttl
+// earlier than with RenewAlways. Touch and TouchMany always renew
+func (cache *Cache[K, V]) SetTouchStrategy(strategy TouchStrategy) {
+	cache.mutex.Lock()
+	cache.renewNear = strategy.near
+	cache.mutex.Unlock()
+}
+
+// SetMaxLifetime caps how long sliding expiration can keep an item alive: touches
+// never extend an item beyond max after it was set, so a constantly read item
+// still expires once it is that old. Unlike SetExpireAfterWrite, it applies to
+// existing items and leaves items that never expire alone
+// A max of 0 means no cap, which is the default
+func (cache *Cache[K, V]) SetMaxLifetime(max time.Duration) {
+	cache.mutex.Lock()
+	if max > 0 && (cache.maxLifetime <= 0 || max < cache.maxLifetime) {
+		cache.capLifetimes(max)
+	}
+	cache.maxLifetime = max
+	cache.mutex.Unlock()
+}
+
+// SetStrictExpiry makes the first deadline of an item final: once it passes the
+// item is never served again, however recently it was read or touched, so that
+// lookups cannot keep an item alive past its intended expiry. Lookups and
+// touches still succeed until then, but no longer extend the item. Unlike SetMaxLifetime, which
+// lets touches extend an item up to a fixed age, the cap is the deadline of the
+// ttl the item was set with, its own or the default one. It applies to every
+// item set afterwards, and leaves items that never expire alone
+// Strict expiry is disabled by default
+func (cache *Cache[K, V]) SetStrictExpiry(strict bool) {
+	cache.mutex.Lock()
+	cache.strictExpiry = strict
+	cache.mutex.Unlock()
+}
+
+// SetExpireAfterWrite sets a lifetime that starts when an item is set and is not
+// extended by lookups, so an item expires once it is that old even if it is
+// constantly read. It applies to every item set afterwards, including those that
+// never expire otherwise. A duration of 0 disables it, which is the default
+func (cache *Cache[K, V]) SetExpireAfterWrite(duration time.Duration) {
+	cache.mutex.Lock()
+	cache.expireAfterWrite = duration
+	cache.mutex.Unlock()
+}
+
+// SetExpireAfterAccess sets how long items without their own ttl live once they
+// are no longer read, in place of the default ttl. Combined with
+// SetExpireAfterWrite, items expire at whichever deadline comes first
+// A duration of 0 restores the default ttl
+func (cache *Cache[K, V]) SetExpireAfterAccess(duration time.Duration) {
+	cache.mutex.Lock()
+	previous, next := cache.ttl, cache.ttl
+	if cache.expireAfterAccess > 0 {
+		previous = cache.expireAfterAccess
+	}
+	if duration > 0 {
+		next = duration
+	}
+	if next < previous {
+		cache.expireBefore(cache.now().Add(next))
+	}
+	cache.expireAfterAccess = duration
+	cache.mutex.Unlock()
+}
+
+// SetJitter spreads the deadlines of items over [ttl, ttl+jitter), so that items
+// set together do not all expire, and get reloaded, at once
+// The offset of an item is picked when it is set and kept when it is touched
+// A jitter of 0 disables it, which is the default
+func (cache *Cache[K, V]) SetJitter(jitter time.Duration) {
+	cache.mutex.Lock()
+	cache.jitter = jitter
+	cache.mutex.Unlock()
+}
+
+// SetSlidingExpiration toggles whether Get extends the life of an item
+// Sliding expiration is enabled by default
+func (cache *Cache[K, V]) SetSlidingExpiration(sliding bool) {
+	cache.mutex.Lock()
+	cache.noSliding = !sliding
+	cache.mutex.Unlock()
+}
+
+// touch extends the life of an item by its own ttl, or the cache default
+func (cache *Cache[K, V]) touch(item *Item[V]) {
+	now := cache.now()
+	item.touch(now, cache.lifetime(item, now))
+}
+
+// renew touches an item for a lookup, unless the touch strategy lets it be,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) renew(item *Item[V]) {
+	if cache.renewNear <= 0 {
+		cache.touch(item)
+		return
+	}
+	now := cache.now()
+	duration := cache.lifetime(item, now)
+	if deadline := item.deadline(); !deadline.IsZero() && duration >= 0 && float64(deadline.Sub(now)) > cache.renewNear*float64(duration) {
+		return
+	}
+	item.touch(now, duration)
+}
+
+// lifetime returns how long touching an item at now extends it, or a negative
+// duration if the item then never expires, following a negative default ttl,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) lifetime(item *Item[V], now time.Time) time.Duration {
+	duration := item.ttl
+	if duration <= 0 {
+		duration = cache.ttl
+		if cache.expireAfterAccess > 0 {
+			duration = cache.expireAfterAccess
+		}
+	}
+	if duration < 0 {
+		if cache.maxTTL <= 0 && cache.maxLifetime <= 0 {
+			return -1
+		}
+		// the ceilings win over never expiring, as in newItem
+		duration = cache.maxTTL
+		if duration <= 0 {
+			duration = item.created.Add(cache.maxLifetime).Sub(now)
+		}
+	} else {
+		duration += item.jitter
+	}
+	if cache.maxTTL > 0 && duration > cache.maxTTL {
+		duration = cache.maxTTL
+	}
+	if cache.maxLifetime > 0 {
+		if limit := item.created.Add(cache.maxLifetime).Sub(now); duration > limit {
+			duration = limit
+		}
+	}
+	return duration
+}
+
+// now returns the current time according to the clock of the cache
+func (cache *Cache[K, V]) now() time.Time {
+	if cache.clock == nil {
+		return time.Now()
+	}
+	return cache.clock.Now()
+}
+
+// expired reports whether an item has outlived its ttl
+func (cache *Cache[K, V]) expired(item *Item[V]) bool {
+	return item.expired(cache.now())
+}
+
+// SetKeysOnlyNotifications stops the notification channels from carrying data:
+// events on Evictions hold the zero value instead, and FinishedItems, which only
+// carries data, receives nothing. Otherwise the channel buffers keep the data
+// of up to Length removed items alive until a consumer drains them, which
+// defeats the cleanup of large values for slow consumers
+// The data is still passed to OnEvicted, which does not retain it
+func (cache *Cache[K, V]) SetKeysOnlyNotifications(keysOnly bool) {
+	cache.mutex.Lock()
+	cache.keysOnly = keysOnly
+	cache.mutex.Unlock()
+}
+
+// DroppedNotifications returns the number of expired items that could not be
+// sent on FinishedItems because its buffer was full
+func (cache *Cache[K, V]) DroppedNotifications() int64 {
+	return atomic.LoadInt64(&cache.dropped)
+}
+
+// DroppedEvents returns the number of eviction events that could not be
+// sent on Evictions because its buffer was full
+func (cache *Cache[K, V]) DroppedEvents() int64 {
+	return atomic.LoadInt64(&cache.droppedEvents)
+}
+
+// Close stops the cleanup goroutine and closes the notification channels
+// Items still in the cache are dropped without notifications, see CloseAndDrain
+// Afterwards stores are no-ops, lookups miss, the cache counts and lists no
+// items and the methods that report errors return ErrClosed. It is safe to
+// call Close more than once
+func (cache *Cache[K, V]) Close() {
+	cache.mutex.Lock()
+	if !cache.closed {
+		cache.stop()
+		cache.dropAll()
+		cache.closeChannels()
+	}
+	cache.mutex.Unlock()
+}
+
+// Closed reports whether Close or CloseAndDrain has been called
+func (cache *Cache[K, V]) Closed() bool {
+	cache.mutex.RLock()
+	closed := cache.closed
+	cache.mutex.RUnlock()
+	return closed
+}
+
+// CloseAndDrain stops the cache like Close, after a final sweep and after
+// reporting every remaining item with the Shutdown reason
+// Unlike other notifications, the events are sent on Evictions without dropping
+// any, so CloseAndDrain blocks until a consumer has received all of them.
+// FinishedItems and Evictions are closed once every event has been sent
+// With the Block overflow policy, swept items also wait for room on FinishedItems
+func (cache *Cache[K, V]) CloseAndDrain() {
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return
+	}
+	cache.stop()
+	evictions := cache.sweep(nil, cache.now())
+	expired := len(evictions)
+	for key, item := range cache.items {
+		evictions = cache.remove(evictions, key, item, Shutdown)
+	}
+	onEvicted := cache.OnEvicted
+	keysOnly := cache.keysOnly
+	cache.mutex.Unlock()
+
+	// blocking sends wait for the consumer here, as the cache is already closed
+	cache.finish(evictions[:expired], nil)
+	for _, e := range evictions {
+		cache.stats.evicted(e.Reason)
+		e := e
+		if onEvicted != nil {
+			cache.dispatch(func() { onEvicted(e.Key, e.Data, e.Reason) })
+		}
+		if cache.OnExpire != nil && e.Reason == Expired {
+			cache.dispatch(func() { cache.OnExpire(e.Key, e.Data) })
+		}
+		if cache.Evictions != nil {
+			event := e
+			if keysOnly {
+				event.Data = *new(V)
+			}
+			cache.Evictions <- event
+		}
+	}
+	cache.mutex.Lock()
+	cache.closeChannels()
+	cache.mutex.Unlock()
+}
+
+// stop marks the cache closed, stops the cleanup goroutine and wakes up
+// the waiters, it must be called with the cache mutex held
+func (cache *Cache[K, V]) stop() {
+	cache.closed = true
+	if cache.done != nil {
+		close(cache.done)
+	}
+	cache.releaseAll()
+	for key := range cache.arrivals {
+		cache.arrive(key)
+	}
+}
+
+// closeChannels closes the notification channels,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) closeChannels() {
+	// wait for the notifications being sent, blocking sends give up once the cache is stopped
+	cache.channelsMutex.Lock()
+	defer cache.channelsMutex.Unlock()
+	cache.channelsClosed = true
+	if cache.FinishedItems != nil {
+		close(cache.FinishedItems)
+	}
+	if cache.Evictions != nil {
+		close(cache.Evictions)
+	}
+	if cache.cleanupDone != nil {
+		close(cache.cleanupDone)
+	}
+	if cache.callbacks != nil && !cache.dispatched {
+		cache.dispatched = true
+		close(cache.callbacks)
+	}
+}
+
+// Cleanup synchronously sweeps the expired items, sending the same notifications
+// as the background sweep, and returns the number of removed items
+// Sweeps only visit the items whose deadline has passed, not the whole cache
+// Sweeps and lookups are serialized by the cache mutex, so an item touched by a
+// lookup before the sweep is kept, and notifications are only sent for removed items
+func (cache *Cache[K, V]) Cleanup() int {
+	return cache.cleanup()
+}
+
+func (cache *Cache[K, V]) cleanup() int {
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return 0
+	}
+	now := cache.now()
+	size := len(cache.items)
+	dropped := cache.DroppedNotifications() + cache.DroppedEvents()
+	onDebug := cache.OnDebug
+	onSlowCleanup := cache.OnSlowCleanup
+	threshold := cache.slowCleanup
+	var held time.Duration
+	var removed, remaining int
+	for {
+		began := time.Now()
+		evictions, more := cache.sweepUpTo(nil, now, cache.cleanupBatch)
+		if !more {
+			for key, failure := range cache.negatives {
+				if !now.Before(failure.expires) {
+					delete(cache.negatives, key)
+				}
+			}
+			cache.lastCleanup = now
+		}
+		remaining = len(cache.items)
+		onEvicted := cache.OnEvicted
+		held += time.Since(began)
+		cache.mutex.Unlock()
+		cache.finish(evictions, cache.done)
+		cache.notify(onEvicted, evictions)
+		removed += len(evictions)
+		if !more {
+			break
+		}
+		// let lookups and stores in before the next batch
+		cache.mutex.Lock()
+		if cache.closed {
+			cache.mutex.Unlock()
+			break
+		}
+	}
+	if threshold > 0 && held > threshold {
+		atomic.AddUint64(&cache.stats.slowCleanups, 1)
+		if onSlowCleanup != nil {
+			onSlowCleanup(held, size)
+		}
+	}
+	if cache.cleanupDone != nil {
+		cache.channelsMutex.RLock()
+		if !cache.channelsClosed {
+			select {
+			case cache.cleanupDone <- removed:
+			default:
+			}
+		}
+		cache.channelsMutex.RUnlock()
+	}
+	if onDebug != nil {
+		onDebug("ttlcache: swept %d expired items, %d items left", removed, remaining)
+		if dropped = cache.DroppedNotifications() + cache.DroppedEvents() - dropped; dropped > 0 {
+			onDebug("ttlcache: dropped %d notifications during the sweep", dropped)
+		}
+	}
+	return removed
+}
+
+// SetCleanupBatchSize bounds the number of expired items a sweep handles per
+// acquisition of the cache mutex, releasing it between batches so that lookups
+// and stores are not held up by a large sweep. Items touched between batches
+// are kept, and the notifications of every batch are sent before the next one
+// A size of 0 sweeps in a single batch, which is the default
+func (cache *Cache[K, V]) SetCleanupBatchSize(size int) {
+	cache.mutex.Lock()
+	cache.cleanupBatch = size
+	cache.mutex.Unlock()
+}
+
+// SetSlowCleanupThreshold sets how long a sweep may hold the cache mutex before
+// it is counted in Stats.SlowCleanups and reported to OnSlowCleanup
+// A threshold of 0 disables the check, which is the default
+func (cache *Cache[K, V]) SetSlowCleanupThreshold(threshold time.Duration) {
+	cache.mutex.Lock()
+	cache.slowCleanup = threshold
+	cache.mutex.Unlock()
+}
+
+// minCleanupInterval is the default floor of the sweep cadence
+const minCleanupInterval = time.Millisecond
+
+// SetCleanupInterval sets how often expired items are swept, independently of the ttl
+// An interval of 0 restores the default of sweeping once per ttl,
+// or once a second if the ttl is not positive
+func (cache *Cache[K, V]) SetCleanupInterval(interval time.Duration) {
+	cache.mutex.Lock()
+	cache.interval = interval
+	cache.reschedule()
+	cache.mutex.Unlock()
+}
+
+// PauseCleanup stops the background sweeps without stopping the cleanup goroutine,
+// such as during a bulk load. Expired items then still miss on lookups, but
+// they are neither removed nor reported until ResumeCleanup. Cleanup still sweeps
+func (cache *Cache[K, V]) PauseCleanup() {
+	cache.mutex.Lock()
+	cache.paused = true
+	cache.mutex.Unlock()
+}
+
+// ResumeCleanup restarts the background sweeps stopped by PauseCleanup, after
+// sweeping the items that expired in the meantime on the calling goroutine
+func (cache *Cache[K, V]) ResumeCleanup() {
+	cache.mutex.Lock()
+	paused := cache.paused
+	cache.paused = false
+	cache.mutex.Unlock()
+	if paused {
+		cache.cleanup()
+	}
+}
+
+// cleanupPaused reports whether PauseCleanup is in effect
+func (cache *Cache[K, V]) cleanupPaused() bool {
+	cache.mutex.RLock()
+	paused := cache.paused
+	cache.mutex.RUnlock()
+	return paused
+}
+
+// SetMinCleanupInterval sets the floor of the sweep cadence, guarding against
+// pathologically tiny ttls or intervals
+// A floor of 0 restores the default of 1ms
+func (cache *Cache[K, V]) SetMinCleanupInterval(floor time.Duration) {
+	cache.mutex.Lock()
+	cache.minInterval = floor
+	cache.reschedule()
+	cache.mutex.Unlock()
+}
+
+// cleanupInterval returns the sweep cadence, it must be called with the cache mutex held
+func (cache *Cache[K, V]) cleanupInterval() time.Duration {
+	duration := cache.interval
+	if duration <= 0 {
+		duration = cache.ttl
+	}
+	if duration <= 0 {
+		return time.Second
+	}
+	floor := cache.minInterval
+	if floor <= 0 {
+		floor = minCleanupInterval
+	}
+	if duration < floor {
+		duration = floor
+	}
+	return duration
+}
+
+// reschedule restarts the cleanup ticker after a change of interval,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) reschedule() {
+	if cache.ticker != nil {
+		interval := cache.cleanupInterval()
+		cache.ticker.Reset(interval)
+		cache.nextCleanup = cache.now().Add(interval)
+	}
+}
+
+// LastCleanup returns when the last sweep completed, or the zero time if none has
+func (cache *Cache[K, V]) LastCleanup() time.Time {
+	cache.mutex.RLock()
+	last := cache.lastCleanup
+	cache.mutex.RUnlock()
+	return last
+}
+
+// NextCleanup returns when the next background sweep is due,
+// or the zero time if the cleaner is not running
+func (cache *Cache[K, V]) NextCleanup() time.Time {
+	cache.mutex.RLock()
+	var next time.Time
+	if cache.cleaning {
+		next = cache.nextCleanup
+	}
+	cache.mutex.RUnlock()
+	return next
+}
+
+// CleanerRunning reports whether the background cleanup goroutine is alive
+func (cache *Cache[K, V]) CleanerRunning() bool {
+	cache.mutex.RLock()
+	running := cache.cleaning
+	cache.mutex.RUnlock()
+	return running
+}
+
+func (cache *Cache[K, V]) startCleanupTimer() {
+	interval := cache.cleanupInterval()
+	ticker := cache.clock.NewTicker(interval)
+	cache.ticker = ticker
+	cache.cleaning = true
+	cache.nextCleanup = cache.now().Add(interval)
+	go (func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.Chan():
+				if !cache.cleanupPaused() {
+					cache.safely(func() { cache.cleanup() })
+				}
+				cache.mutex.Lock()
+				cache.nextCleanup = cache.now().Add(cache.cleanupInterval())
+				cache.mutex.Unlock()
+			case <-cache.done:
+				cache.mutex.Lock()
+				cache.cleaning = false
+				cache.mutex.Unlock()
+				return
+			}
+		}
+	})()
+}
+
+// StringCache is the string-valued cache returned by NewCache
+type StringCache = Cache[string, string]
+
+// NewCache is a helper to create instance of the Cache struct holding strings
+// A negative duration stores items that never expire. A zero duration, which
+// NewCacheWithConfig rejects, is clamped to a negative one instead of storing
+// items that expire right away
+func NewCache(duration time.Duration) *Cache[string, string] {
+	if duration == 0 {
+		duration = -1
+	}
+	return New[string, string](duration)
+}
+
+// NewCacheLazy is a helper to create instance of the Cache struct holding strings,
+// without a background cleanup goroutine
+// Expired items keep their memory until Cleanup is called, so the cache grows
+// with every key that is set and never read or deleted again
+func NewCacheLazy(duration time.Duration) *Cache[string, string] {
+	return NewWithConfig[string, string](Config{TTL: duration, NoCleanup: true})
+}
+
+// NewCacheWithConfig creates a cache holding strings from cfg after validating it,
+// returning the error of Validate for an invalid configuration
+func NewCacheWithConfig(cfg Config) (*Cache[string, string], error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return NewWithConfig[string, string](cfg), nil
+}
+
+// New is a helper to create instance of the Cache struct holding values of type V
+// under keys of type K
+func New[K comparable, V any](duration time.Duration) *Cache[K, V] {
+	return NewWithConfig[K, V](Config{TTL: duration})
+}
+
+// Config holds the settings used to construct a Cache
+type Config struct {
+	// TTL is the default lifetime of items, negative for items that never expire
+	TTL time.Duration
+	// Length is the buffer size of FinishedItems and Evictions, 10 if zero
+	Length int
+	// Clock is the source of time of the cache, the real time if nil
+	Clock Clock
+	// DispatchBuffer, if positive, runs OnEvicted and OnAccess in order on a
+	// dedicated goroutine, decoupled from the operations that trigger them,
+	// queueing up to DispatchBuffer invocations before dropping them
+	DispatchBuffer int
+	// Overflow decides what happens to notifications once the buffer of
+	// FinishedItems or Evictions is full, DropNewest if zero. Disabled leaves
+	// both channels nil
+	Overflow OverflowPolicy
+	// InitialCapacity sizes the map of items, and the new map of Flush, so that
+	// filling the cache up to it does not grow the map on the way
+	InitialCapacity int
+	// CleanupDone allocates the CleanupDone channel of the cache
+	CleanupDone bool
+	// NoCleanup skips the background cleanup goroutine, expired items then only
+	// miss on lookups and their memory is only reclaimed by Cleanup, Delete or Flush
+	NoCleanup bool
+	// MaxItems caps the number of items as with SetMaxItems, unlimited if zero
+	MaxItems int
+	// MaxBytes caps the total size of the values as with SetMaxBytes, unlimited if zero
+	MaxBytes int64
+}
+
+// ErrInvalidConfig is returned by Validate and NewCacheWithConfig for a
+// configuration that cannot work, wrapped with the reason
+var ErrInvalidConfig = errors.New("ttlcache: invalid config")
+
+// Validate reports the first setting of cfg that cannot work: a TTL of 0, which
+// expires every item right away, a negative Length, DispatchBuffer,
+// InitialCapacity, MaxItems or MaxBytes, an unknown Overflow policy, or a Length
+// with the Disabled policy and no CleanupDone channel for it to size
+func (cfg Config) Validate() error {
+	switch {
+	case cfg.TTL == 0:
+		return fmt.Errorf("%w: TTL must be positive, or negative for items that never expire", ErrInvalidConfig)
+	case cfg.Length < 0:
+		return fmt.Errorf("%w: negative Length %d", ErrInvalidConfig, cfg.Length)
+	case cfg.DispatchBuffer < 0:
+		return fmt.Errorf("%w: negative DispatchBuffer %d", ErrInvalidConfig, cfg.DispatchBuffer)
+	case cfg.InitialCapacity < 0:
+		return fmt.Errorf("%w: negative InitialCapacity %d", ErrInvalidConfig, cfg.InitialCapacity)
+	case cfg.MaxItems < 0:
+		return fmt.Errorf("%w: negative MaxItems %d", ErrInvalidConfig, cfg.MaxItems)
+	case cfg.MaxBytes < 0:
+		return fmt.Errorf("%w: negative MaxBytes %d", ErrInvalidConfig, cfg.MaxBytes)
+	case cfg.Overflow < DropNewest || cfg.Overflow > Disabled:
+		return fmt.Errorf("%w: unknown Overflow policy %d", ErrInvalidConfig, cfg.Overflow)
+	case cfg.Overflow == Disabled && cfg.Length > 0 && !cfg.CleanupDone:
+		return fmt.Errorf("%w: Length sizes no channel with the Disabled Overflow policy", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// NewWithConfig is a helper to create instance of the Cache struct from a Config
+// The config is not validated, Validate reports the settings that cannot work
+func NewWithConfig[K comparable, V any](cfg Config) *Cache[K, V] {
+	length := cfg.Length
+	if length == 0 {
+		length = 10
+	}
+	clock := cfg.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+	cache := &Cache[K, V]{
+		ttl:      cfg.TTL,
+		items:    make(map[K]*Item[V], cfg.InitialCapacity),
+		calls:    map[K]*call[V]{},
+		Length:   length,
+		clock:    clock,
+		done:     make(chan struct{}),
+		overflow: cfg.Overflow,
+		capacity: cfg.InitialCapacity,
+		maxItems: cfg.MaxItems,
+		maxBytes: cfg.MaxBytes,
+	}
+	if cfg.Overflow != Disabled {
+		cache.FinishedItems = make(chan V, cache.Length)
+		cache.Evictions = make(chan EvictionEvent[K, V], cache.Length)
+	}
+	if cfg.CleanupDone {
+		cache.cleanupDone = make(chan int, cache.Length)
+		cache.CleanupDone = cache.cleanupDone
+	}
+	if cfg.DispatchBuffer > 0 {
+		cache.startDispatcher(cfg.DispatchBuffer)
+	}
+	if !cfg.NoCleanup {
+		cache.startCleanupTimer()
 	}
-	cache.FinishedItems = make(chan string, cache.Length)
-	cache.startCleanupTimer()
 	return cache
 }
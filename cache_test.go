@@ -1,15 +1,24 @@
 package ttlcache
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 func TestGet(t *testing.T) {
-	cache := &Cache{
+	cache := &Cache[string, string]{
 		ttl:   time.Second,
-		items: map[string]*Item{},
+		items: map[string]*Item[string]{},
 	}
 
 	data, exists := cache.Get("hello")
@@ -31,9 +40,9 @@ func TestExpiration(t *testing.T) {
 	cache := NewCache(time.Second)
 
 	/*
-		cache := &Cache{
+		cache := &Cache[string, string]{
 			ttl:   time.Second,
-			items: map[string]*Item{},
+			items: map[string]*Item[string]{},
 		}
 	*/
 	cache.Set("x", "1")
@@ -50,10 +59,10 @@ func TestExpiration(t *testing.T) {
 
 	<-time.After(500 * time.Millisecond)
 	cache.mutex.Lock()
-	cache.items["y"].touch(time.Second)
+	cache.items["y"].touch(time.Now(), time.Second)
 	item, exists := cache.items["x"]
 	cache.mutex.Unlock()
-	if !exists || item.data != "1" || item.expired() {
+	if !exists || item.data != "1" || item.expired(time.Now()) {
 		t.Errorf("Expected `x` to not have expired after 200ms")
 	}
 
@@ -113,3 +122,2885 @@ func TestExpiration(t *testing.T) {
 
 	time.Sleep(time.Second * 10)
 }
+
+func TestClose(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	caches := make([]*Cache[string, string], 50)
+	for i := range caches {
+		caches[i] = NewCache(time.Second)
+		caches[i].Set("hello", "world")
+	}
+	for _, cache := range caches {
+		cache.Close()
+		cache.Close()
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if count := runtime.NumGoroutine(); count > baseline {
+		t.Errorf("Expected goroutine count to return to %d, got %d", baseline, count)
+	}
+
+	cache := caches[0]
+	cache.Set("foo", "bar")
+	if _, exists := cache.Get("hello"); exists {
+		t.Errorf("Expected closed cache to return no data")
+	}
+	if _, exists := cache.Get("foo"); exists {
+		t.Errorf("Expected Set on closed cache to be a no-op")
+	}
+	if _, open := <-cache.FinishedItems; open {
+		t.Errorf("Expected FinishedItems to be closed")
+	}
+}
+
+func TestNewCacheZeroTTL(t *testing.T) {
+	cache := NewCache(0)
+	defer cache.Close()
+	if ttl := cache.TTL(); ttl >= 0 {
+		t.Errorf("Expected a zero ttl to be clamped to never expire, got %s", ttl)
+	}
+	cache.Set("hello", "world")
+	<-time.After(10 * time.Millisecond)
+	if _, exists := cache.Get("hello"); !exists {
+		t.Errorf("Expected items of a cache created with a zero ttl to never expire")
+	}
+}
+
+func TestCloseDropsItems(t *testing.T) {
+	cache := NewCache(time.Second)
+	cache.SetWithTags("hello", "world", "greeting")
+	cache.Close()
+
+	if count := cache.Count(); count != 0 {
+		t.Errorf("Expected a closed cache to count no items, got %d", count)
+	}
+	if keys := cache.Keys(); len(keys) != 0 {
+		t.Errorf("Expected a closed cache to list no keys, got %v", keys)
+	}
+	if size := cache.SizeBytes(); size != 0 {
+		t.Errorf("Expected a closed cache to hold no bytes, got %d", size)
+	}
+}
+
+func TestClosedEnumeration(t *testing.T) {
+	cache := NewCache(time.Second)
+	cache.Set("hello", "world")
+	cache.Close()
+
+	if keys, err := cache.KeysMatch("*"); err != ErrClosed || len(keys) != 0 {
+		t.Errorf("Expected KeysMatch to return ErrClosed, got %v, %v", keys, err)
+	}
+	if snapshot := cache.Snapshot(); len(snapshot) != 0 {
+		t.Errorf("Expected a closed cache to have an empty snapshot, got %v", snapshot)
+	}
+	if entries := cache.Entries(); len(entries) != 0 {
+		t.Errorf("Expected a closed cache to have no entries, got %v", entries)
+	}
+	if exported := cache.Export(); len(exported) != 0 {
+		t.Errorf("Expected a closed cache to export nothing, got %v", exported)
+	}
+	if live := cache.CountLive(); live != 0 {
+		t.Errorf("Expected a closed cache to count no live items, got %d", live)
+	}
+	visited := 0
+	cache.Range(func(key string, data string) bool {
+		visited++
+		return true
+	})
+	if visited != 0 {
+		t.Errorf("Expected Range to visit no items of a closed cache, visited %d", visited)
+	}
+	if encoded, err := json.Marshal(cache); err != nil || string(encoded) != "{}" {
+		t.Errorf("Expected a closed cache to serialize as an empty object, got %s, %v", encoded, err)
+	}
+}
+
+func TestClosedErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+	cache := NewCache(time.Second)
+	cache.Set("hello", "world")
+	if err := cache.SaveFile(path); err != nil {
+		t.Fatalf("Expected cache to save, got %v", err)
+	}
+	if cache.Closed() {
+		t.Errorf("Expected an open cache to not report being closed")
+	}
+	cache.Close()
+	if !cache.Closed() {
+		t.Errorf("Expected a closed cache to report being closed")
+	}
+
+	called := false
+	if _, err := cache.GetOrCompute("hello", func() (string, error) {
+		called = true
+		return "value", nil
+	}); err != ErrClosed || called {
+		t.Errorf("Expected GetOrCompute to return ErrClosed without loading, got %v", err)
+	}
+	if _, _, err := cache.GetContext(context.Background(), "hello"); err != ErrClosed {
+		t.Errorf("Expected GetContext to return ErrClosed, got %v", err)
+	}
+	if err := cache.SetJSON("json", 1); err != ErrClosed {
+		t.Errorf("Expected SetJSON to return ErrClosed, got %v", err)
+	}
+	var dest int
+	if _, err := cache.GetJSON("json", &dest); err != ErrClosed {
+		t.Errorf("Expected GetJSON to return ErrClosed, got %v", err)
+	}
+	if _, err := cache.Increment("counter", 1); err != ErrClosed {
+		t.Errorf("Expected Increment to return ErrClosed, got %v", err)
+	}
+	if _, err := cache.LoadFrom(strings.NewReader("a\t1\n")); err != ErrClosed {
+		t.Errorf("Expected LoadFrom to return ErrClosed, got %v", err)
+	}
+	if err := cache.SaveFile(path); err != ErrClosed {
+		t.Errorf("Expected SaveFile to return ErrClosed, got %v", err)
+	}
+	if err := cache.LoadFile(path); err != ErrClosed {
+		t.Errorf("Expected LoadFile to return ErrClosed, got %v", err)
+	}
+
+	cache.Set("foo", "bar")
+	cache.SetWithTTL("foo", "bar", time.Minute)
+	cache.Delete("hello")
+	cache.Flush()
+	if _, exists := cache.Get("foo"); exists {
+		t.Errorf("Expected stores on a closed cache to be no-ops")
+	}
+}
+
+func TestGenericInt(t *testing.T) {
+	cache := New[string, int](time.Second)
+	defer cache.Close()
+
+	data, exists := cache.Get("answer")
+	if exists || data != 0 {
+		t.Errorf("Expected empty cache to return the zero value")
+	}
+
+	cache.Set("answer", 42)
+	data, exists = cache.Get("answer")
+	if !exists || data != 42 {
+		t.Errorf("Expected cache to return 42 for `answer`")
+	}
+}
+
+func TestGenericStruct(t *testing.T) {
+	type user struct {
+		Name string
+		Age  int
+	}
+	cache := New[string, user](time.Second)
+	defer cache.Close()
+
+	data, exists := cache.Get("alice")
+	if exists || data != (user{}) {
+		t.Errorf("Expected empty cache to return the zero value")
+	}
+
+	cache.Set("alice", user{Name: "Alice", Age: 30})
+	data, exists = cache.Get("alice")
+	if !exists || data.Name != "Alice" || data.Age != 30 {
+		t.Errorf("Expected cache to return the stored struct for `alice`")
+	}
+}
+
+func TestGenericPointer(t *testing.T) {
+	type user struct {
+		Name string
+	}
+	cache := New[string, *user](time.Second)
+	defer cache.Close()
+
+	data, exists := cache.Get("bob")
+	if exists || data != nil {
+		t.Errorf("Expected empty cache to return nil")
+	}
+
+	bob := &user{Name: "Bob"}
+	cache.Set("bob", bob)
+	data, exists = cache.Get("bob")
+	if !exists || data != bob {
+		t.Errorf("Expected cache to return the stored pointer for `bob`")
+	}
+}
+
+func TestSetWithTTL(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.SetWithTTL("short", "1", 100*time.Millisecond)
+	cache.SetWithTTL("long", "2", 10*time.Second)
+	cache.SetWithTTL("forever", "3", -1)
+	cache.SetWithTTL("default", "4", 0)
+
+	<-time.After(200 * time.Millisecond)
+	if _, exists := cache.Get("short"); exists {
+		t.Errorf("Expected `short` to have expired")
+	}
+	cache.mutex.RLock()
+	if cache.items["default"].expired(time.Now()) {
+		t.Errorf("Expected `default` to not have expired after 200ms")
+	}
+	cache.mutex.RUnlock()
+
+	<-time.After(2300 * time.Millisecond)
+	cache.mutex.RLock()
+	if _, exists := cache.items["short"]; exists {
+		t.Errorf("Expected `short` to have been cleaned up")
+	}
+	if _, exists := cache.items["default"]; exists {
+		t.Errorf("Expected `default` to have been cleaned up")
+	}
+	if _, exists := cache.items["long"]; !exists {
+		t.Errorf("Expected `long` to survive a cleanup cycle")
+	}
+	if _, exists := cache.items["forever"]; !exists {
+		t.Errorf("Expected `forever` to survive a cleanup cycle")
+	}
+	cache.mutex.RUnlock()
+}
+
+func TestSlidingExpirationDisabled(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.SetSlidingExpiration(false)
+
+	cache.Set("hello", "world")
+	for i := 0; i < 8; i++ {
+		if _, exists := cache.Get("hello"); !exists {
+			t.Errorf("Expected `hello` to not have expired yet")
+		}
+		<-time.After(100 * time.Millisecond)
+	}
+
+	<-time.After(300 * time.Millisecond)
+	if _, exists := cache.Get("hello"); exists {
+		t.Errorf("Expected `hello` to expire at its original deadline")
+	}
+}
+
+func TestPeek(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	if _, exists := cache.Peek("hello"); exists {
+		t.Errorf("Expected empty cache to return no data")
+	}
+
+	cache.Set("hello", "world")
+	for i := 0; i < 8; i++ {
+		data, exists := cache.Peek("hello")
+		if !exists || data != "world" {
+			t.Errorf("Expected cache to return `world` for `hello`")
+		}
+		<-time.After(100 * time.Millisecond)
+	}
+
+	<-time.After(300 * time.Millisecond)
+	if _, exists := cache.Peek("hello"); exists {
+		t.Errorf("Expected `hello` to expire despite being peeked")
+	}
+}
+
+func TestOnEvicted(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	var mutex sync.Mutex
+	reasons := map[string][]EvictionReason{}
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		// calling back into the cache must not deadlock
+		cache.Count()
+		mutex.Lock()
+		reasons[key] = append(reasons[key], reason)
+		mutex.Unlock()
+	}
+
+	cache.Set("replaced", "1")
+	cache.SetWithTTL("replaced", "2", 100*time.Millisecond)
+	cache.Set("deleted", "3")
+	cache.Delete("deleted")
+	cache.Delete("missing")
+	cache.SetWithTTL("expired", "4", 100*time.Millisecond)
+
+	<-time.After(1500 * time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	expected := map[string][]EvictionReason{
+		"replaced": {Replaced, Expired},
+		"deleted":  {Deleted},
+		"expired":  {Expired},
+	}
+	if len(reasons) != len(expected) {
+		t.Errorf("Expected callbacks for %d keys, got %v", len(expected), reasons)
+	}
+	for key, want := range expected {
+		got := reasons[key]
+		if len(got) != len(want) {
+			t.Errorf("Expected %v for `%s`, got %v", want, key, got)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("Expected %v for `%s`, got %v", want, key, got)
+			}
+		}
+	}
+}
+
+func TestDroppedNotifications(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	for i := 0; i < cache.Length; i++ {
+		cache.SetWithTTL(fmt.Sprintf("key %d", i), "old", 100*time.Millisecond)
+	}
+	<-time.After(1500 * time.Millisecond)
+	if dropped := cache.DroppedNotifications(); dropped != 0 {
+		t.Errorf("Expected no dropped notifications, got %d", dropped)
+	}
+
+	for i := 0; i < 5; i++ {
+		cache.SetWithTTL(fmt.Sprintf("key %d", i), "new", 100*time.Millisecond)
+	}
+	<-time.After(time.Second)
+	if dropped := cache.DroppedNotifications(); dropped != 5 {
+		t.Errorf("Expected 5 dropped notifications, got %d", dropped)
+	}
+
+	for i := 0; i < cache.Length; i++ {
+		if data := <-cache.FinishedItems; data != "old" {
+			t.Errorf("Expected the oldest notifications to be kept, got %s", data)
+		}
+	}
+}
+
+func TestGetOrSet(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	actual, loaded := cache.GetOrSet("hello", "world")
+	if loaded || actual != "world" {
+		t.Errorf("Expected GetOrSet to store `world` for `hello`")
+	}
+	actual, loaded = cache.GetOrSet("hello", "there")
+	if !loaded || actual != "world" {
+		t.Errorf("Expected GetOrSet to load `world` for `hello`")
+	}
+
+	var wg sync.WaitGroup
+	var stores int32
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, loaded := cache.GetOrSet("race", fmt.Sprintf("value %d", i)); !loaded {
+				atomic.AddInt32(&stores, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if stores != 1 {
+		t.Errorf("Expected exactly one GetOrSet to store, got %d", stores)
+	}
+}
+
+func TestGetOrSetAccounting(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	var accessed []string
+	cache.OnAccess = func(key string) {
+		accessed = append(accessed, key)
+	}
+
+	cache.GetOrSet("hello", "world")
+	cache.GetOrSet("hello", "there")
+	cache.Get("hello")
+	if info, _ := cache.GetEntry("hello"); info.AccessCount != 3 {
+		t.Errorf("Expected the hit of GetOrSet to count as an access, got %d", info.AccessCount)
+	}
+	if len(accessed) != 3 {
+		t.Errorf("Expected OnAccess for every hit, got %v", accessed)
+	}
+	if stats := cache.Stats(); stats.Hits != 3 || stats.Misses != 1 {
+		t.Errorf("Expected 3 hits and 1 miss, got %+v", stats)
+	}
+}
+
+func TestCleanupInterval(t *testing.T) {
+	cache := NewCache(time.Hour)
+	defer cache.Close()
+	cache.SetCleanupInterval(100 * time.Millisecond)
+
+	cache.SetWithTTL("short", "lived", 50*time.Millisecond)
+	cache.Set("long", "lived")
+
+	<-time.After(300 * time.Millisecond)
+	cache.mutex.RLock()
+	if _, exists := cache.items["short"]; exists {
+		t.Errorf("Expected `short` to have been swept at the short cadence")
+	}
+	if _, exists := cache.items["long"]; !exists {
+		t.Errorf("Expected `long` to not have been swept")
+	}
+	cache.mutex.RUnlock()
+}
+
+func TestRange(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+	cache.Set("c", "3")
+	cache.SetWithTTL("expired", "4", time.Millisecond)
+	<-time.After(10 * time.Millisecond)
+
+	visited := map[string]string{}
+	cache.Range(func(key string, data string) bool {
+		visited[key] = data
+		return true
+	})
+	if len(visited) != 3 || visited["a"] != "1" || visited["b"] != "2" || visited["c"] != "3" {
+		t.Errorf("Expected Range to visit the 3 live items, got %v", visited)
+	}
+
+	calls := 0
+	cache.Range(func(key string, data string) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("Expected Range to stop after the first item, got %d calls", calls)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+	cache.SetWithTTL("expired", "3", time.Millisecond)
+	<-time.After(10 * time.Millisecond)
+
+	keys := cache.Keys()
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("Expected Keys to return the 2 live keys, got %v", keys)
+	}
+
+	keys[0] = "mutated"
+	if _, exists := cache.Get("a"); !exists {
+		t.Errorf("Expected mutating the keys to not affect the cache")
+	}
+	if _, exists := cache.Get("mutated"); exists {
+		t.Errorf("Expected mutating the keys to not affect the cache")
+	}
+}
+
+func TestFlush(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	flushed := 0
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		if reason == Flushed {
+			flushed++
+		}
+	}
+
+	for i := 0; i < 50; i++ {
+		cache.Set(fmt.Sprintf("key %d", i), fmt.Sprintf("value %d", i))
+	}
+	cache.Flush()
+
+	if count := cache.Count(); count != 0 {
+		t.Errorf("Expected flushed cache to be empty, got %d items", count)
+	}
+	for i := 0; i < 50; i++ {
+		if _, exists := cache.Get(fmt.Sprintf("key %d", i)); exists {
+			t.Errorf("Expected `key %d` to have been flushed", i)
+		}
+	}
+	if flushed != 50 {
+		t.Errorf("Expected 50 flushed callbacks, got %d", flushed)
+	}
+
+	cache.Set("hello", "world")
+	if _, exists := cache.Get("hello"); !exists {
+		t.Errorf("Expected flushed cache to accept new items")
+	}
+}
+
+func TestFlushEvictions(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+	cache.Flush()
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-cache.Evictions:
+			if event.Reason != Flushed {
+				t.Errorf("Expected a Flushed event, got %+v", event)
+			}
+		default:
+			t.Errorf("Expected Flush to publish an event per item without callbacks")
+		}
+	}
+	if evictions := cache.Stats().Evictions; evictions != 2 {
+		t.Errorf("Expected Flush to count 2 evictions, got %d", evictions)
+	}
+}
+
+func TestConfigLength(t *testing.T) {
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Length: 25})
+	defer cache.Close()
+	if cap(cache.FinishedItems) != 25 || cache.Length != 25 {
+		t.Errorf("Expected FinishedItems to have a buffer of 25, got %d", cap(cache.FinishedItems))
+	}
+
+	cache = NewCache(time.Second)
+	defer cache.Close()
+	if cap(cache.FinishedItems) != 10 || cache.Length != 10 {
+		t.Errorf("Expected FinishedItems to have a default buffer of 10, got %d", cap(cache.FinishedItems))
+	}
+}
+
+func TestSetTTL(t *testing.T) {
+	cache := NewCache(time.Hour)
+	defer cache.Close()
+	if ttl := cache.TTL(); ttl != time.Hour {
+		t.Errorf("Expected TTL to return 1h, got %s", ttl)
+	}
+
+	cache.Set("before", "1")
+	cache.SetTTL(100 * time.Millisecond)
+	if ttl := cache.TTL(); ttl != 100*time.Millisecond {
+		t.Errorf("Expected TTL to return 100ms, got %s", ttl)
+	}
+	cache.Set("after", "2")
+
+	<-time.After(200 * time.Millisecond)
+	if _, exists := cache.Peek("after"); exists {
+		t.Errorf("Expected `after` to expire with the new ttl")
+	}
+	if _, exists := cache.Peek("before"); !exists {
+		t.Errorf("Expected `before` to keep its original deadline")
+	}
+}
+
+func TestTTLRemaining(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.SetWithTTL("hello", "world", 300*time.Millisecond)
+	cache.SetWithTTL("forever", "young", -1)
+
+	first, exists := cache.TTLRemaining("hello")
+	if !exists || first <= 0 || first > 300*time.Millisecond {
+		t.Errorf("Expected `hello` to have up to 300ms remaining, got %s", first)
+	}
+	<-time.After(100 * time.Millisecond)
+	second, exists := cache.TTLRemaining("hello")
+	if !exists || second >= first {
+		t.Errorf("Expected remaining ttl to decrease, got %s then %s", first, second)
+	}
+
+	data, expiresAt, exists := cache.GetWithExpiration("hello")
+	if !exists || data != "world" || time.Until(expiresAt) > second {
+		t.Errorf("Expected GetWithExpiration to not extend the life of `hello`")
+	}
+	if remaining, exists := cache.TTLRemaining("forever"); !exists || remaining >= 0 {
+		t.Errorf("Expected `forever` to report a negative remaining ttl")
+	}
+
+	<-time.After(300 * time.Millisecond)
+	if _, exists := cache.TTLRemaining("hello"); exists {
+		t.Errorf("Expected expired `hello` to report not found")
+	}
+	if _, exists := cache.TTLRemaining("missing"); exists {
+		t.Errorf("Expected missing key to report not found")
+	}
+}
+
+func TestSetManyGetMany(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.SetMany(map[string]string{"a": "1", "b": "2", "c": "3"})
+	cache.SetWithTTL("expired", "4", time.Millisecond)
+	<-time.After(10 * time.Millisecond)
+
+	if count := cache.Count(); count != 4 {
+		t.Errorf("Expected cache to contain 4 items, got %d", count)
+	}
+
+	result := cache.GetMany([]string{"a", "c", "missing", "expired"})
+	if len(result) != 2 || result["a"] != "1" || result["c"] != "3" {
+		t.Errorf("Expected GetMany to return only the live hits, got %v", result)
+	}
+	if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 2 {
+		t.Errorf("Expected GetMany to count 2 hits and 2 misses, got %+v", stats)
+	}
+}
+
+func BenchmarkSetLoop(b *testing.B) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	items := benchmarkItems()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for key, data := range items {
+			cache.Set(key, data)
+		}
+	}
+}
+
+func BenchmarkSetMany(b *testing.B) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	items := benchmarkItems()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.SetMany(items)
+	}
+}
+
+func BenchmarkGetLoop(b *testing.B) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	items := benchmarkItems()
+	cache.SetMany(items)
+	keys := cache.Keys()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, key := range keys {
+			cache.Get(key)
+		}
+	}
+}
+
+func BenchmarkGetMany(b *testing.B) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	items := benchmarkItems()
+	cache.SetMany(items)
+	keys := cache.Keys()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.GetMany(keys)
+	}
+}
+
+func benchmarkWarmUp(b *testing.B, capacity int) {
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key %d", i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache := NewWithConfig[string, string](Config{TTL: time.Minute, InitialCapacity: capacity, NoCleanup: true})
+		for _, key := range keys {
+			cache.Set(key, "value")
+		}
+		cache.Close()
+	}
+}
+
+func BenchmarkWarmUp(b *testing.B) {
+	benchmarkWarmUp(b, 0)
+}
+
+func BenchmarkWarmUpInitialCapacity(b *testing.B) {
+	benchmarkWarmUp(b, 10000)
+}
+
+func benchmarkItems() map[string]string {
+	items := make(map[string]string, 100)
+	for i := 0; i < 100; i++ {
+		items[fmt.Sprintf("key %d", i)] = fmt.Sprintf("value %d", i)
+	}
+	return items
+}
+
+func TestDeletePrefix(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.SetMany(map[string]string{
+		"user:1:name":  "alice",
+		"user:1:email": "alice@example.com",
+		"user:2:name":  "bob",
+		"session:1":    "token",
+	})
+
+	if deleted := cache.DeletePrefix("user:3:"); deleted != 0 {
+		t.Errorf("Expected no matches to delete 0 items, got %d", deleted)
+	}
+	if deleted := cache.DeletePrefix("user:1:"); deleted != 2 {
+		t.Errorf("Expected `user:1:` to delete 2 items, got %d", deleted)
+	}
+	if _, exists := cache.Get("user:2:name"); !exists {
+		t.Errorf("Expected `user:2:name` to not have been deleted")
+	}
+	if count := cache.Count(); count != 2 {
+		t.Errorf("Expected cache to contain 2 items, got %d", count)
+	}
+	if deleted := cache.DeletePrefix(""); deleted != 2 {
+		t.Errorf("Expected an empty prefix to delete 2 items, got %d", deleted)
+	}
+	if count := cache.Count(); count != 0 {
+		t.Errorf("Expected cache to be empty, got %d items", count)
+	}
+}
+
+func TestDeleteMatch(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.SetMany(map[string]string{
+		"user:1:name":  "alice",
+		"user:1:email": "alice@example.com",
+		"user:2:name":  "bob",
+		"user:a:name":  "carol",
+		"session:1":    "token",
+	})
+
+	if _, err := cache.DeleteMatch("user:[1-"); err != filepath.ErrBadPattern {
+		t.Errorf("Expected a malformed pattern to return ErrBadPattern, got %v", err)
+	}
+	if count := cache.Count(); count != 5 {
+		t.Errorf("Expected a malformed pattern to delete nothing, got %d items", count)
+	}
+
+	keys, err := cache.KeysMatch("user:*:name")
+	sort.Strings(keys)
+	if err != nil || fmt.Sprint(keys) != "[user:1:name user:2:name user:a:name]" {
+		t.Errorf("Expected `user:*:name` to match 3 keys, got %v (%v)", keys, err)
+	}
+	if _, err := cache.KeysMatch("["); err != filepath.ErrBadPattern {
+		t.Errorf("Expected a malformed pattern to return ErrBadPattern, got %v", err)
+	}
+
+	if deleted, err := cache.DeleteMatch("user:[0-9]:*"); err != nil || deleted != 3 {
+		t.Errorf("Expected `user:[0-9]:*` to delete 3 items, got %d (%v)", deleted, err)
+	}
+	if _, exists := cache.Get("user:a:name"); !exists {
+		t.Errorf("Expected `user:a:name` to not have been deleted")
+	}
+	if deleted, _ := cache.DeleteMatch("session:?"); deleted != 1 {
+		t.Errorf("Expected `session:?` to delete 1 item, got %d", deleted)
+	}
+	if deleted, _ := cache.DeleteMatch("*:*:*"); deleted != 1 {
+		t.Errorf("Expected `*:*:*` to delete 1 item, got %d", deleted)
+	}
+	if count := cache.Count(); count != 0 {
+		t.Errorf("Expected cache to be empty, got %d items", count)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.Set("hello", "world")
+	if !cache.Replace("hello", "there") {
+		t.Errorf("Expected Replace to update the live `hello`")
+	}
+	if data, _ := cache.Get("hello"); data != "there" {
+		t.Errorf("Expected `hello` to have been replaced with `there`, got %s", data)
+	}
+
+	if cache.Replace("missing", "value") {
+		t.Errorf("Expected Replace to not update a missing key")
+	}
+	if _, exists := cache.Get("missing"); exists {
+		t.Errorf("Expected Replace to not store a missing key")
+	}
+
+	cache.SetWithTTL("expired", "value", time.Millisecond)
+	<-time.After(10 * time.Millisecond)
+	if cache.Replace("expired", "again") {
+		t.Errorf("Expected Replace to treat an expired key as missing")
+	}
+	if _, exists := cache.Get("expired"); exists {
+		t.Errorf("Expected Replace to not resurrect an expired key")
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Minute, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	cache.Set("hello", "world")
+	clock.Advance(30 * time.Second)
+	if !cache.CompareAndSwap("hello", "world", "there") {
+		t.Errorf("Expected a matching old value to swap")
+	}
+	if remaining, _ := cache.TTLRemaining("hello"); remaining != time.Minute {
+		t.Errorf("Expected a swap to refresh the life of the item, got %v remaining", remaining)
+	}
+	if cache.CompareAndSwap("hello", "world", "again") {
+		t.Errorf("Expected a mismatched old value to not swap")
+	}
+	if data, _ := cache.Peek("hello"); data != "there" {
+		t.Errorf("Expected `hello` to hold `there`, got %s", data)
+	}
+	if cache.CompareAndSwap("missing", "", "value") {
+		t.Errorf("Expected a missing key to not swap")
+	}
+	if _, exists := cache.Peek("missing"); exists {
+		t.Errorf("Expected a failed swap to not create `missing`")
+	}
+	clock.Advance(2 * time.Minute)
+	if cache.CompareAndSwap("hello", "there", "again") {
+		t.Errorf("Expected an expired item to not swap")
+	}
+
+	slices := New[string, []byte](time.Second)
+	defer slices.Close()
+	slices.Set("data", []byte("abc"))
+	if !slices.CompareAndSwap("data", []byte("abc"), []byte("def")) {
+		t.Errorf("Expected byte slices to be compared by content")
+	}
+}
+
+func TestAdd(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	if !cache.Add("hello", "world") {
+		t.Errorf("Expected Add to store a fresh key")
+	}
+	if cache.Add("hello", "there") {
+		t.Errorf("Expected Add to not store over a live key")
+	}
+	if data, _ := cache.Get("hello"); data != "world" {
+		t.Errorf("Expected `hello` to be unchanged, got %s", data)
+	}
+
+	cache.SetWithTTL("expired", "value", time.Millisecond)
+	<-time.After(10 * time.Millisecond)
+	if !cache.Add("expired", "again") {
+		t.Errorf("Expected Add to store over an expired key")
+	}
+	if data, _ := cache.Get("expired"); data != "again" {
+		t.Errorf("Expected `expired` to have been replaced, got %s", data)
+	}
+}
+
+func TestSubSecondCleanup(t *testing.T) {
+	cache := NewCache(20 * time.Millisecond)
+	defer cache.Close()
+
+	cache.Set("hello", "world")
+	select {
+	case data := <-cache.FinishedItems:
+		if data != "world" {
+			t.Errorf("Expected `world` to be notified, got %s", data)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Errorf("Expected `hello` to be swept within a small multiple of its ttl")
+	}
+
+	cache.SetMinCleanupInterval(time.Hour)
+	cache.mutex.RLock()
+	interval := cache.cleanupInterval()
+	cache.mutex.RUnlock()
+	if interval != time.Hour {
+		t.Errorf("Expected the cleanup interval to be clamped to its floor, got %s", interval)
+	}
+}
+
+func TestTouchKey(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.SetWithTTL("touched", "1", 200*time.Millisecond)
+	cache.SetWithTTL("untouched", "2", 200*time.Millisecond)
+	for i := 0; i < 5; i++ {
+		<-time.After(100 * time.Millisecond)
+		if !cache.Touch("touched") {
+			t.Errorf("Expected Touch to find the live `touched`")
+		}
+	}
+
+	if _, exists := cache.Peek("touched"); !exists {
+		t.Errorf("Expected `touched` to stay alive past its original deadline")
+	}
+	if _, exists := cache.Peek("untouched"); exists {
+		t.Errorf("Expected `untouched` to have expired")
+	}
+	if cache.Touch("untouched") || cache.Touch("missing") {
+		t.Errorf("Expected Touch to report expired and missing keys")
+	}
+	if _, exists := cache.Peek("untouched"); exists {
+		t.Errorf("Expected Touch to not resurrect `untouched`")
+	}
+}
+
+func TestGetAndSet(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	if old, existed := cache.GetAndSet("hello", "world"); existed || old != "" {
+		t.Errorf("Expected GetAndSet to report no previous item")
+	}
+	if old, existed := cache.GetAndSet("hello", "there"); !existed || old != "world" {
+		t.Errorf("Expected GetAndSet to return the previous `world`")
+	}
+	if data, _ := cache.Get("hello"); data != "there" {
+		t.Errorf("Expected `hello` to have been set to `there`, got %s", data)
+	}
+
+	cache.SetWithTTL("expired", "value", time.Millisecond)
+	<-time.After(10 * time.Millisecond)
+	if _, existed := cache.GetAndSet("expired", "again"); existed {
+		t.Errorf("Expected GetAndSet to treat an expired key as missing")
+	}
+}
+
+func TestGetAndDelete(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.Set("token", "secret")
+	var wg sync.WaitGroup
+	var consumed int32
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if old, existed := cache.GetAndDelete("token"); existed && old == "secret" {
+				atomic.AddInt32(&consumed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if consumed != 1 {
+		t.Errorf("Expected exactly one GetAndDelete to consume the token, got %d", consumed)
+	}
+	if _, exists := cache.Get("token"); exists {
+		t.Errorf("Expected `token` to have been deleted")
+	}
+
+	cache.SetWithTTL("expired", "value", time.Millisecond)
+	<-time.After(10 * time.Millisecond)
+	if _, existed := cache.GetAndDelete("expired"); existed {
+		t.Errorf("Expected GetAndDelete to treat an expired key as missing")
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	cache := NewCache(time.Hour)
+	defer cache.Close()
+
+	cache.SetWithTTL("a", "1", 10*time.Millisecond)
+	cache.SetWithTTL("b", "2", 10*time.Millisecond)
+	cache.Set("c", "3")
+	<-time.After(50 * time.Millisecond)
+
+	if count := cache.Count(); count != 3 {
+		t.Errorf("Expected expired items to not have been swept yet, got %d items", count)
+	}
+	if removed := cache.Cleanup(); removed != 2 {
+		t.Errorf("Expected Cleanup to remove 2 items, got %d", removed)
+	}
+	if count := cache.Count(); count != 1 {
+		t.Errorf("Expected cache to contain 1 item, got %d", count)
+	}
+	if len(cache.FinishedItems) != 2 {
+		t.Errorf("Expected Cleanup to notify 2 items, got %d", len(cache.FinishedItems))
+	}
+}
+
+func TestHas(t *testing.T) {
+	cache := NewCache(time.Hour)
+	defer cache.Close()
+
+	cache.SetWithTTL("present", "1", 100*time.Millisecond)
+	cache.SetWithTTL("expired", "2", time.Millisecond)
+	<-time.After(10 * time.Millisecond)
+
+	if !cache.Has("present") {
+		t.Errorf("Expected Has to report `present`")
+	}
+	if cache.Has("missing") {
+		t.Errorf("Expected Has to not report `missing`")
+	}
+	if cache.Has("expired") {
+		t.Errorf("Expected Has to not report the unswept `expired`")
+	}
+
+	<-time.After(100 * time.Millisecond)
+	if cache.Has("present") {
+		t.Errorf("Expected Has to not extend the life of `present`")
+	}
+}
+
+func TestLastCleanup(t *testing.T) {
+	cache := NewCache(time.Second)
+	cache.SetCleanupInterval(50 * time.Millisecond)
+
+	if !cache.CleanerRunning() {
+		t.Errorf("Expected the cleaner to be running")
+	}
+	if last := cache.LastCleanup(); !last.IsZero() {
+		t.Errorf("Expected no sweep to have run yet, got %s", last)
+	}
+	if next := cache.NextCleanup(); next.Before(time.Now()) || next.After(time.Now().Add(50*time.Millisecond)) {
+		t.Errorf("Expected the next sweep within 50ms, got %s", next)
+	}
+
+	<-time.After(120 * time.Millisecond)
+	if last := cache.LastCleanup(); last.IsZero() || time.Since(last) > 100*time.Millisecond {
+		t.Errorf("Expected a recent sweep, got %s", last)
+	}
+
+	cache.Close()
+	deadline := time.Now().Add(time.Second)
+	for cache.CleanerRunning() && time.Now().Before(deadline) {
+		<-time.After(5 * time.Millisecond)
+	}
+	if cache.CleanerRunning() {
+		t.Errorf("Expected the cleaner to stop once the cache is closed")
+	}
+	if next := cache.NextCleanup(); !next.IsZero() {
+		t.Errorf("Expected no next sweep once the cache is closed, got %s", next)
+	}
+}
+
+func TestEvictionEvents(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.Set("deleted", "1")
+	cache.Delete("deleted")
+	cache.SetWithTTL("expired", "2", 10*time.Millisecond)
+	<-time.After(20 * time.Millisecond)
+	cache.Cleanup()
+
+	expected := []EvictionEvent[string, string]{
+		{Key: "deleted", Data: "1", Reason: Deleted},
+		{Key: "expired", Data: "2", Reason: Expired},
+	}
+	for _, want := range expected {
+		select {
+		case event := <-cache.Evictions:
+			if event.Key != want.Key || event.Data != want.Data || event.Reason != want.Reason {
+				t.Errorf("Expected event %+v, got %+v", want, event)
+			}
+			if event.At.IsZero() || time.Since(event.At) > time.Second {
+				t.Errorf("Expected the event to carry the eviction time, got %s", event.At)
+			}
+		default:
+			t.Errorf("Expected an event for `%s`", want.Key)
+		}
+	}
+}
+
+func TestDroppedEvents(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	for i := 0; i < cache.Length+3; i++ {
+		cache.Set(fmt.Sprintf("key %d", i), "value")
+		cache.Delete(fmt.Sprintf("key %d", i))
+	}
+	if dropped := cache.DroppedEvents(); dropped != 3 {
+		t.Errorf("Expected 3 dropped events, got %d", dropped)
+	}
+	if event := <-cache.Evictions; event.Key != "key 0" {
+		t.Errorf("Expected the oldest events to be kept, got %s", event.Key)
+	}
+}
+
+func TestConcurrentReads(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				key := fmt.Sprintf("key %d", j%50)
+				switch (i + j) % 4 {
+				case 0:
+					cache.SetWithTTL(key, "value", time.Millisecond)
+				case 1:
+					cache.Get(key)
+				case 2:
+					cache.Peek(key)
+				case 3:
+					cache.GetMany([]string{key, "missing"})
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	cache.SetMaxItems(10)
+	cache.Set("hello", "world")
+	if data, exists := cache.Get("hello"); !exists || data != "world" {
+		t.Errorf("Expected cache to return `world` for `hello`")
+	}
+}
+
+func BenchmarkParallelGet(b *testing.B) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	cache.SetMany(benchmarkItems())
+	benchmarkParallelGet(b, cache)
+}
+
+func BenchmarkParallelGetBounded(b *testing.B) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	cache.SetMaxItems(1000)
+	cache.SetMany(benchmarkItems())
+	benchmarkParallelGet(b, cache)
+}
+
+func benchmarkParallelGet(b *testing.B, cache *Cache[string, string]) {
+	keys := cache.Keys()
+	var counter int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := atomic.AddInt64(&counter, 1)
+			cache.Get(keys[i%int64(len(keys))])
+		}
+	})
+}
+
+func TestGetBeforeCleanupKeepsItem(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	var notified []string
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		notified = append(notified, key)
+	}
+	cache.Set("hello", "world")
+
+	clock.Advance(900 * time.Millisecond)
+	cache.Get("hello")
+	clock.Advance(200 * time.Millisecond)
+	if removed := cache.Cleanup(); removed != 0 {
+		t.Errorf("Expected a touched item to survive the sweep, %d removed", removed)
+	}
+	if _, exists := cache.Get("hello"); !exists {
+		t.Errorf("Expected `hello` to still be in the cache")
+	}
+	if len(notified) != 0 {
+		t.Errorf("Expected no notification for a kept item, got %v", notified)
+	}
+
+	clock.Advance(1100 * time.Millisecond)
+	if removed := cache.Cleanup(); removed != 1 {
+		t.Errorf("Expected the expired item to be swept, %d removed", removed)
+	}
+	if len(notified) != 1 {
+		t.Errorf("Expected a single notification for the swept item, got %v", notified)
+	}
+}
+
+func TestConcurrentGetAndCleanup(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	var mutex sync.Mutex
+	notified := map[string]int{}
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		mutex.Lock()
+		notified[key]++
+		mutex.Unlock()
+	}
+	for i := 0; i < 100; i++ {
+		cache.SetWithTTL(fmt.Sprintf("key %d", i), "value", time.Duration(i%10)*time.Millisecond+time.Millisecond)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				for j := 0; j < 100; j += 3 {
+					cache.Get(fmt.Sprintf("key %d", j))
+				}
+			}
+		}()
+	}
+	removed := 0
+	for i := 0; i < 20; i++ {
+		removed += cache.Cleanup()
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(notified) != removed {
+		t.Errorf("Expected %d notifications, got %d", removed, len(notified))
+	}
+	for key, count := range notified {
+		if count != 1 {
+			t.Errorf("Expected `%s` to be notified once, got %d", key, count)
+		}
+		if _, exists := cache.Peek(key); exists {
+			t.Errorf("Expected notified `%s` to have been removed", key)
+		}
+	}
+	if count := removed + cache.Count(); count != 100 {
+		t.Errorf("Expected swept and remaining items to add up to 100, got %d", count)
+	}
+}
+
+func TestMaxTTL(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Hour, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.SetMaxTTL(time.Minute)
+
+	cache.SetWithTTL("huge", "value", 24*time.Hour)
+	cache.SetWithTTL("forever", "value", -1)
+	cache.SetWithTTL("short", "value", 30*time.Second)
+	cache.Set("default", "value")
+
+	if remaining, _ := cache.TTLRemaining("huge"); remaining > time.Minute {
+		t.Errorf("Expected a huge ttl to be clamped to a minute, got %v", remaining)
+	}
+	if remaining, _ := cache.TTLRemaining("short"); remaining != 30*time.Second {
+		t.Errorf("Expected a ttl within the ceiling to be honored, got %v", remaining)
+	}
+
+	clock.Advance(45 * time.Second)
+	if _, exists := cache.Peek("short"); exists {
+		t.Errorf("Expected `short` to have expired")
+	}
+	for _, key := range []string{"huge", "forever", "default"} {
+		if _, exists := cache.Peek(key); !exists {
+			t.Errorf("Expected `%s` to live until the ceiling", key)
+		}
+	}
+	clock.Advance(20 * time.Second)
+	for _, key := range []string{"huge", "forever", "default"} {
+		if _, exists := cache.Peek(key); exists {
+			t.Errorf("Expected `%s` to have expired at the ceiling", key)
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.Set("hello", "world")
+	cache.Set("foo", "bar")
+	cache.SetWithTTL("short", "value", 10*time.Millisecond)
+	<-time.After(50 * time.Millisecond)
+
+	snapshot := cache.Snapshot()
+	if len(snapshot) != 2 || snapshot["hello"] != "world" || snapshot["foo"] != "bar" {
+		t.Errorf("Expected a snapshot of the 2 live items, got %v", snapshot)
+	}
+
+	snapshot["hello"] = "changed"
+	delete(snapshot, "foo")
+	if data, _ := cache.Get("hello"); data != "world" {
+		t.Errorf("Expected changing the snapshot to leave the cache as it was, got %s", data)
+	}
+	if _, exists := cache.Get("foo"); !exists {
+		t.Errorf("Expected deleting from the snapshot to leave `foo` in the cache")
+	}
+
+	cache.Set("new", "value")
+	cache.Delete("hello")
+	if _, exists := snapshot["new"]; exists {
+		t.Errorf("Expected changing the cache to leave the snapshot as it was")
+	}
+	if snapshot["hello"] != "changed" {
+		t.Errorf("Expected deleting from the cache to leave `hello` in the snapshot")
+	}
+}
+
+func TestExpiresAt(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Hour, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	start := clock.Now()
+
+	cache.SetWithTTL("gone", "value", time.Second)
+	cache.SetWithTTL("soon", "value", time.Minute)
+	cache.SetWithTTL("later", "value", 10*time.Minute)
+	cache.SetWithTTL("forever", "value", -1)
+	clock.Advance(2 * time.Second)
+
+	if expiresAt, exists := cache.ExpiresAt("soon"); !exists || !expiresAt.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected `soon` to expire a minute after being set, got %v", expiresAt)
+	}
+	if expiresAt, exists := cache.ExpiresAt("forever"); !exists || !expiresAt.IsZero() {
+		t.Errorf("Expected `forever` to report the zero time, got %v", expiresAt)
+	}
+	if _, exists := cache.ExpiresAt("gone"); exists {
+		t.Errorf("Expected an expired but unswept item to be missing")
+	}
+
+	keys := cache.ExpiringBefore(start.Add(5 * time.Minute))
+	if len(keys) != 1 || keys[0] != "soon" {
+		t.Errorf("Expected only `soon` to expire within 5 minutes, got %v", keys)
+	}
+	keys = cache.ExpiringBefore(start.Add(time.Hour))
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[later soon]" {
+		t.Errorf("Expected `later` and `soon` to expire within an hour, got %v", keys)
+	}
+
+	if remaining, _ := cache.TTLRemaining("soon"); remaining != time.Minute-2*time.Second {
+		t.Errorf("Expected inspecting deadlines to not extend `soon`, got %v remaining", remaining)
+	}
+}
+
+func TestIntKeys(t *testing.T) {
+	cache := New[int, string](time.Second)
+	defer cache.Close()
+
+	var evicted []int
+	cache.OnEvicted = func(key int, data string, reason EvictionReason) {
+		evicted = append(evicted, key)
+	}
+	cache.SetMaxItems(2)
+	cache.Set(1, "one")
+	cache.Set(2, "two")
+	cache.Set(3, "three")
+
+	if data, exists := cache.Get(3); !exists || data != "three" {
+		t.Errorf("Expected cache to return `three` for 3")
+	}
+	if len(evicted) != 1 || evicted[0] != 1 {
+		t.Errorf("Expected 1 to have been evicted, got %v", evicted)
+	}
+	keys := cache.Keys()
+	sort.Ints(keys)
+	if fmt.Sprint(keys) != "[2 3]" {
+		t.Errorf("Expected keys 2 and 3, got %v", keys)
+	}
+	if deleted := cache.DeletePrefix(""); deleted != 0 {
+		t.Errorf("Expected keys that are not strings to never match a prefix, %d deleted", deleted)
+	}
+}
+
+func TestStructKeys(t *testing.T) {
+	type point struct{ x, y int }
+	cache := New[point, string](time.Second)
+	defer cache.Close()
+
+	cache.Set(point{1, 2}, "a")
+	cache.Set(point{1, 2}, "b")
+	if count := cache.Count(); count != 1 {
+		t.Errorf("Expected equal keys to refer to the same item, got %d items", count)
+	}
+	if data, exists := cache.Get(point{1, 2}); !exists || data != "b" {
+		t.Errorf("Expected cache to return `b` for {1 2}")
+	}
+	if _, exists := cache.Get(point{2, 1}); exists {
+		t.Errorf("Expected {2 1} to be missing")
+	}
+	if keys, _ := cache.KeysMatch("*"); len(keys) != 0 {
+		t.Errorf("Expected keys that are not strings to never match a pattern, got %v", keys)
+	}
+}
+
+func TestNamedStringKeys(t *testing.T) {
+	type userID string
+	cache := New[userID, string](time.Second)
+	defer cache.Close()
+
+	cache.Set("user:1", "alice")
+	cache.Set("user:2", "bob")
+	cache.Set("admin:1", "carol")
+	if deleted := cache.DeletePrefix("user:"); deleted != 2 {
+		t.Errorf("Expected named string keys to match a prefix, %d deleted", deleted)
+	}
+	if keys, _ := cache.KeysMatch("admin:?"); len(keys) != 1 || keys[0] != "admin:1" {
+		t.Errorf("Expected named string keys to match a pattern, got %v", keys)
+	}
+}
+
+func TestOnAccess(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	accessed := map[string]int{}
+	cache.OnAccess = func(key string) {
+		accessed[key]++
+		cache.Peek(key)
+	}
+	cache.Set("hello", "world")
+	cache.Set("foo", "bar")
+	cache.SetWithTTL("short", "value", 10*time.Millisecond)
+	<-time.After(50 * time.Millisecond)
+
+	cache.Get("hello")
+	cache.Get("hello")
+	cache.Get("missing")
+	cache.Get("short")
+	cache.GetMany([]string{"foo", "missing", "short"})
+	cache.Peek("foo")
+
+	if len(accessed) != 2 || accessed["hello"] != 2 || accessed["foo"] != 1 {
+		t.Errorf("Expected OnAccess to fire once per hit, got %v", accessed)
+	}
+}
+
+func TestLazyCache(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	cache := NewCacheLazy(10 * time.Millisecond)
+	defer cache.Close()
+
+	if cache.CleanerRunning() {
+		t.Errorf("Expected a lazy cache to not run the cleaner")
+	}
+	if count := runtime.NumGoroutine(); count > baseline {
+		t.Errorf("Expected a lazy cache to not start a goroutine, got %d more", count-baseline)
+	}
+	cache.SetCleanupInterval(time.Millisecond)
+
+	cache.Set("hello", "world")
+	<-time.After(50 * time.Millisecond)
+	if _, exists := cache.Get("hello"); exists {
+		t.Errorf("Expected `hello` to have expired")
+	}
+	if count := cache.Count(); count != 1 {
+		t.Errorf("Expected the expired item to be kept until a sweep, got %d items", count)
+	}
+	if removed := cache.Cleanup(); removed != 1 {
+		t.Errorf("Expected an explicit sweep to remove the expired item, %d removed", removed)
+	}
+}
+
+func TestCountLive(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	cache.Set("hello", "world")
+	cache.SetWithTTL("short", "value", 10*time.Millisecond)
+	cache.SetWithTTL("shorter", "value", 5*time.Millisecond)
+	<-time.After(50 * time.Millisecond)
+
+	if count := cache.Count(); count != 3 {
+		t.Errorf("Expected Count to include unswept items, got %d", count)
+	}
+	if count := cache.CountLive(); count != 1 {
+		t.Errorf("Expected CountLive to skip expired items, got %d", count)
+	}
+	cache.Cleanup()
+	if cache.Count() != cache.CountLive() {
+		t.Errorf("Expected Count and CountLive to agree after a sweep")
+	}
+}
+
+func TestCloseAndDrain(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Minute, Length: 1, Clock: clock})
+	cache.SetCleanupInterval(time.Hour)
+	for i := 0; i < 20; i++ {
+		cache.Set(fmt.Sprintf("key %d", i), "value")
+	}
+	cache.SetWithTTL("short", "value", time.Second)
+	clock.Advance(2 * time.Second)
+
+	var callbacks int64
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		atomic.AddInt64(&callbacks, 1)
+	}
+	received := map[string]EvictionReason{}
+	done := make(chan struct{})
+	go func() {
+		for event := range cache.Evictions {
+			received[event.Key] = event.Reason
+		}
+		close(done)
+	}()
+	cache.CloseAndDrain()
+	cache.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected Evictions to be closed once drained")
+	}
+	if len(received) != 21 {
+		t.Errorf("Expected every remaining item to be reported, got %d", len(received))
+	}
+	if received["short"] != Expired || received["key 0"] != Shutdown {
+		t.Errorf("Expected expired items to be swept and the others to be shut down, got %v", received)
+	}
+	if callbacks != 21 {
+		t.Errorf("Expected OnEvicted for every remaining item, got %d", callbacks)
+	}
+	if data, open := <-cache.FinishedItems; !open || data != "value" {
+		t.Errorf("Expected the swept item on FinishedItems")
+	}
+	if _, open := <-cache.FinishedItems; open {
+		t.Errorf("Expected FinishedItems to be closed")
+	}
+	if count := cache.Count(); count != 0 {
+		t.Errorf("Expected a drained cache to be empty, got %d items", count)
+	}
+}
+
+func TestAddWithTTL(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Hour, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	if !cache.AddWithTTL("lease", "owner 1", time.Second) {
+		t.Errorf("Expected a fresh key to be stored")
+	}
+	if remaining, _ := cache.TTLRemaining("lease"); remaining != time.Second {
+		t.Errorf("Expected the lease to use its own ttl, got %v remaining", remaining)
+	}
+	if cache.AddWithTTL("lease", "owner 2", time.Second) {
+		t.Errorf("Expected a held lease to not be taken")
+	}
+	clock.Advance(2 * time.Second)
+	if !cache.AddWithTTL("lease", "owner 2", time.Second) {
+		t.Errorf("Expected an expired lease to be taken")
+	}
+	if data, _ := cache.Peek("lease"); data != "owner 2" {
+		t.Errorf("Expected `owner 2` to hold the lease, got %s", data)
+	}
+}
+
+func TestAddWithTTLContended(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	var stored int64
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if cache.AddWithTTL("lease", fmt.Sprintf("owner %d", i), time.Minute) {
+				atomic.AddInt64(&stored, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if stored != 1 {
+		t.Errorf("Expected a single caller to take the lease, got %d", stored)
+	}
+}
+
+func TestOnDebug(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Minute, Length: 1, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	var messages []string
+	cache.OnDebug = func(format string, args ...interface{}) {
+		messages = append(messages, fmt.Sprintf(format, args...))
+	}
+	cache.Set("hello", "world")
+	cache.Get("hello")
+	if len(messages) != 0 {
+		t.Errorf("Expected no messages outside of sweeps, got %v", messages)
+	}
+
+	cache.SetWithTTL("a", "value", time.Second)
+	cache.SetWithTTL("b", "value", time.Second)
+	clock.Advance(2 * time.Second)
+	cache.Cleanup()
+
+	expected := []string{
+		"ttlcache: swept 2 expired items, 1 items left",
+		"ttlcache: dropped 2 notifications during the sweep",
+	}
+	if fmt.Sprint(messages) != fmt.Sprint(expected) {
+		t.Errorf("Expected messages %q, got %q", expected, messages)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Minute, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.SetJitter(10 * time.Second)
+
+	deadlines := map[time.Time]bool{}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key %d", i)
+		cache.Set(key, "value")
+		expiresAt, _ := cache.ExpiresAt(key)
+		if offset := expiresAt.Sub(clock.Now()); offset < time.Minute || offset >= time.Minute+10*time.Second {
+			t.Errorf("Expected `%s` to expire within the jitter window, got %v", key, offset)
+		}
+		deadlines[expiresAt] = true
+	}
+	if len(deadlines) < 50 {
+		t.Errorf("Expected deadlines to be spread over the jitter window, got %d distinct", len(deadlines))
+	}
+
+	before, _ := cache.ExpiresAt("key 0")
+	clock.Advance(time.Second)
+	cache.Get("key 0")
+	if after, _ := cache.ExpiresAt("key 0"); after.Sub(before) != time.Second {
+		t.Errorf("Expected a touch to keep the offset of the item, moved by %v", after.Sub(before))
+	}
+}
+
+func TestExpireAfterAccess(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Hour, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.SetExpireAfterWrite(time.Hour)
+	cache.SetExpireAfterAccess(10 * time.Second)
+
+	cache.Set("idle", "value")
+	cache.Set("busy", "value")
+	for i := 0; i < 3; i++ {
+		clock.Advance(5 * time.Second)
+		cache.Get("busy")
+	}
+	if _, exists := cache.Peek("idle"); exists {
+		t.Errorf("Expected a young item to expire once idle")
+	}
+	if _, exists := cache.Peek("busy"); !exists {
+		t.Errorf("Expected a read item to be kept alive")
+	}
+	if removed := cache.Cleanup(); removed != 1 {
+		t.Errorf("Expected the idle item to be swept, %d removed", removed)
+	}
+}
+
+func TestExpireAfterWrite(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Hour, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.SetExpireAfterWrite(30 * time.Second)
+	cache.SetExpireAfterAccess(10 * time.Second)
+	start := clock.Now()
+
+	cache.Set("busy", "value")
+	cache.SetWithTTL("forever", "value", -1)
+	if expiresAt, _ := cache.ExpiresAt("forever"); !expiresAt.Equal(start.Add(30 * time.Second)) {
+		t.Errorf("Expected the write ttl to apply to items that never expire, got %v", expiresAt)
+	}
+	for i := 0; i < 5; i++ {
+		clock.Advance(5 * time.Second)
+		if _, exists := cache.Get("busy"); !exists {
+			t.Errorf("Expected `busy` to be live after %v", clock.Now().Sub(start))
+		}
+	}
+	if expiresAt, _ := cache.ExpiresAt("busy"); !expiresAt.Equal(start.Add(30 * time.Second)) {
+		t.Errorf("Expected the deadline to be capped by the write ttl, got %v", expiresAt.Sub(start))
+	}
+	clock.Advance(6 * time.Second)
+	if _, exists := cache.Get("busy"); exists {
+		t.Errorf("Expected `busy` to expire after the write ttl despite constant reads")
+	}
+	if removed := cache.Cleanup(); removed != 2 {
+		t.Errorf("Expected both items to be swept, %d removed", removed)
+	}
+}
+
+func TestVersions(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.Set("hello", "world")
+	_, first, found := cache.GetVersioned("hello")
+	if !found || first == 0 {
+		t.Errorf("Expected `hello` to have a version")
+	}
+	cache.Set("hello", "world")
+	_, second, _ := cache.GetVersioned("hello")
+	if second <= first {
+		t.Errorf("Expected a write to advance the version, got %d after %d", second, first)
+	}
+
+	if cache.CompareVersionAndSwap("hello", first, "stale") {
+		t.Errorf("Expected a swap at a stale version to fail")
+	}
+	if !cache.CompareVersionAndSwap("hello", second, "there") {
+		t.Errorf("Expected a swap at the current version to succeed")
+	}
+	data, third, _ := cache.GetVersioned("hello")
+	if data != "there" || third <= second {
+		t.Errorf("Expected the swap to store `there` at a new version, got %s at %d", data, third)
+	}
+
+	cache.Delete("hello")
+	cache.Set("hello", "there")
+	if _, fourth, _ := cache.GetVersioned("hello"); fourth <= third || cache.CompareVersionAndSwap("hello", third, "aba") {
+		t.Errorf("Expected setting a deleted key again to not reuse its version")
+	}
+	if _, _, found := cache.GetVersioned("missing"); found || cache.CompareVersionAndSwap("missing", 0, "value") {
+		t.Errorf("Expected a missing key to have no version")
+	}
+}
+
+func TestKeysOnlyNotifications(t *testing.T) {
+	cache := New[string, *[]byte](time.Hour)
+	defer cache.Close()
+	cache.SetKeysOnlyNotifications(true)
+
+	collected := make(chan struct{})
+	blob := make([]byte, 1<<20)
+	data := &blob
+	runtime.SetFinalizer(data, func(*[]byte) { close(collected) })
+	cache.Set("blob", data)
+	data = nil
+	cache.Delete("blob")
+
+	timeout := time.After(time.Second)
+collect:
+	for {
+		runtime.GC()
+		select {
+		case <-collected:
+			break collect
+		case <-timeout:
+			t.Fatalf("Expected the evicted data to not be retained by the channels")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if event := <-cache.Evictions; event.Key != "blob" || event.Data != nil || event.Reason != Deleted {
+		t.Errorf("Expected a keys only event for `blob`, got %+v", event)
+	}
+	if len(cache.FinishedItems) != 0 {
+		t.Errorf("Expected nothing to be sent on FinishedItems")
+	}
+}
+
+func TestKeyFunc(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.KeyFunc = strings.ToLower
+
+	cache.Set("KEY", "value")
+	if data, found := cache.Get("key"); !found || data != "value" {
+		t.Errorf("Expected `KEY` to be retrievable as `key`")
+	}
+	if data, found := cache.Peek("Key"); !found || data != "value" {
+		t.Errorf("Expected `KEY` to be retrievable as `Key`")
+	}
+	if keys := cache.Keys(); len(keys) != 1 || keys[0] != "key" {
+		t.Errorf("Expected the normalized key to be stored, got %v", keys)
+	}
+	if result := cache.GetMany([]string{"KEY"}); result["key"] != "value" {
+		t.Errorf("Expected GetMany to report the normalized key, got %v", result)
+	}
+
+	cache.Set("Prefix:One", "1")
+	if removed := cache.DeletePrefix("prefix:"); removed != 1 {
+		t.Errorf("Expected prefixes to match the normalized keys, removed %d", removed)
+	}
+	cache.Delete("kEy")
+	if cache.Count() != 0 {
+		t.Errorf("Expected Delete to normalize the key")
+	}
+}
+
+func TestSetPermanent(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.SetMaxTTL(time.Minute)
+	cache.SetExpireAfterWrite(time.Minute)
+
+	cache.SetPermanent("permanent", "value")
+	for i := 0; i < 3; i++ {
+		cache.Set(fmt.Sprintf("ttl %d", i), "value")
+		cache.SetWithTTL(fmt.Sprintf("negative %d", i), "value", -1)
+		clock.Advance(time.Hour)
+		if removed := cache.Cleanup(); removed != 2 {
+			t.Errorf("Expected the ttl items around the permanent one to expire, %d removed", removed)
+		}
+		if data, found := cache.Get("permanent"); !found || data != "value" {
+			t.Errorf("Expected the permanent item to survive cleanup %d", i)
+		}
+	}
+	if expiresAt, _ := cache.ExpiresAt("permanent"); !expiresAt.IsZero() {
+		t.Errorf("Expected the permanent item to never expire, got %s", expiresAt)
+	}
+
+	cache.SetMaxItems(1)
+	cache.Set("other", "value")
+	if cache.Has("permanent") || cache.Count() != 1 {
+		t.Errorf("Expected the permanent item to count towards the limits")
+	}
+	cache.SetPermanent("permanent", "value")
+	cache.Delete("permanent")
+	if cache.Has("permanent") {
+		t.Errorf("Expected the permanent item to be deleted")
+	}
+}
+
+func TestCleanupWithoutConsumer(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.Length = 0
+	cache.FinishedItems = make(chan string)
+	cache.Evictions = make(chan EvictionEvent[string, string])
+
+	for i := 0; i < 3; i++ {
+		cache.Set(fmt.Sprintf("key %d", i), "value")
+	}
+	clock.Advance(2 * time.Second)
+	done := make(chan int)
+	go func() { done <- cache.Cleanup() }()
+	select {
+	case removed := <-done:
+		if removed != 3 {
+			t.Errorf("Expected 3 items to be swept, %d removed", removed)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected cleanup to complete without a consumer")
+	}
+
+	cache.Set("hello", "world")
+	if data, found := cache.Get("hello"); !found || data != "world" {
+		t.Errorf("Expected the cache to keep working after the sweep")
+	}
+	if dropped := cache.DroppedNotifications(); dropped != 3 {
+		t.Errorf("Expected 3 dropped notifications, got %d", dropped)
+	}
+}
+
+func TestTTLFunc(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.TTLFunc = func(key string, data string) time.Duration {
+		switch data {
+		case "short":
+			return time.Minute
+		case "long":
+			return time.Hour
+		case "forever":
+			return -1
+		}
+		return 0
+	}
+
+	now := clock.Now()
+	cache.Set("a", "short")
+	cache.Set("b", "long")
+	cache.Set("c", "forever")
+	cache.Set("d", "default")
+	cache.SetWithTTL("e", "short", 2*time.Hour)
+	expected := map[string]time.Time{
+		"a": now.Add(time.Minute),
+		"b": now.Add(time.Hour),
+		"c": {},
+		"d": now.Add(time.Second),
+		"e": now.Add(2 * time.Hour),
+	}
+	for key, want := range expected {
+		if expiresAt, _ := cache.ExpiresAt(key); !expiresAt.Equal(want) {
+			t.Errorf("Expected `%s` to expire at %s, got %s", key, want, expiresAt)
+		}
+	}
+}
+
+func TestEntries(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, []byte](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	cache.Set("hello", []byte("world"))
+	cache.Set("expired", []byte("value"))
+	cache.Get("hello")
+	cache.Get("hello")
+	cache.GetMany([]string{"hello", "missing"})
+	cache.Peek("hello")
+	clock.Advance(500 * time.Millisecond)
+	cache.Get("hello")
+	clock.Advance(600 * time.Millisecond)
+
+	entries := cache.Entries()
+	if len(entries) != 1 {
+		t.Errorf("Expected only the live item, got %d entries", len(entries))
+	}
+	info := entries["hello"]
+	if info.AccessCount != 4 {
+		t.Errorf("Expected 4 accesses, got %d", info.AccessCount)
+	}
+	if want := clock.Now().Add(400 * time.Millisecond); !info.ExpiresAt.Equal(want) {
+		t.Errorf("Expected the item to expire at %s, got %s", want, info.ExpiresAt)
+	}
+	info.Data[0] = 'W'
+	if data, _ := cache.Peek("hello"); string(data) != "world" {
+		t.Errorf("Expected the entry data to be a detached copy, the cache holds %s", data)
+	}
+}
+
+func TestMaxLifetime(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.Set("hot", "value")
+	cache.SetWithTTL("forever", "value", -1)
+	cache.SetMaxLifetime(5 * time.Second)
+
+	start := clock.Now()
+	for clock.Now().Sub(start) < 5*time.Second {
+		if _, found := cache.Get("hot"); !found {
+			t.Fatalf("Expected the hot item to live until its max lifetime")
+		}
+		clock.Advance(100 * time.Millisecond)
+	}
+	if expiresAt, _ := cache.ExpiresAt("hot"); !expiresAt.Equal(start.Add(5 * time.Second)) {
+		t.Errorf("Expected touches to stop extending the item at its max lifetime, got %s", expiresAt.Sub(start))
+	}
+	clock.Advance(time.Millisecond)
+	if _, found := cache.Get("hot"); found {
+		t.Errorf("Expected the hot item to expire despite constant access")
+	}
+	if removed := cache.Cleanup(); removed != 1 {
+		t.Errorf("Expected the hot item to be swept, %d removed", removed)
+	}
+	if !cache.Has("forever") {
+		t.Errorf("Expected items that never expire to be left alone")
+	}
+}
+
+func TestStrictExpiry(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.SetStrictExpiry(true)
+	cache.Set("hot", "value")
+	cache.SetWithTTL("own", "value", time.Minute)
+	cache.SetWithTTL("forever", "value", -1)
+
+	start := clock.Now()
+	clock.Advance(999 * time.Millisecond)
+	if _, found := cache.Get("hot"); !found {
+		t.Fatalf("Expected the item to be served before its deadline")
+	}
+	cache.Touch("hot")
+	if expiresAt, _ := cache.ExpiresAt("hot"); !expiresAt.Equal(start.Add(time.Second)) {
+		t.Errorf("Expected touches to not move the first deadline, got %s", expiresAt.Sub(start))
+	}
+	clock.Advance(2 * time.Millisecond)
+	if _, found := cache.Get("hot"); found {
+		t.Errorf("Expected the item read right before its deadline to be gone at it")
+	}
+	if removed := cache.Cleanup(); removed != 1 {
+		t.Errorf("Expected the item to be swept at its first deadline, %d removed", removed)
+	}
+
+	clock.Advance(30 * time.Second)
+	cache.Get("own")
+	if expiresAt, _ := cache.ExpiresAt("own"); !expiresAt.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected the own ttl to set the first deadline, got %s", expiresAt.Sub(start))
+	}
+	clock.Advance(time.Hour)
+	if !cache.Has("forever") {
+		t.Errorf("Expected items that never expire to be left alone")
+	}
+
+	cache.SetStrictExpiry(false)
+	cache.Set("sliding", "value")
+	clock.Advance(900 * time.Millisecond)
+	cache.Get("sliding")
+	clock.Advance(900 * time.Millisecond)
+	if _, found := cache.Get("sliding"); !found {
+		t.Errorf("Expected lookups to extend items once strict expiry is disabled")
+	}
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	overflow := func(policy OverflowPolicy) *Cache[string, string] {
+		cache := NewWithConfig[string, string](Config{TTL: time.Second, Length: 2, Overflow: policy})
+		for i := 0; i < 4; i++ {
+			cache.Set(fmt.Sprintf("key %d", i), "value")
+			cache.Delete(fmt.Sprintf("key %d", i))
+		}
+		return cache
+	}
+	received := func(cache *Cache[string, string]) []string {
+		var keys []string
+		for len(cache.Evictions) > 0 {
+			keys = append(keys, (<-cache.Evictions).Key)
+		}
+		return keys
+	}
+
+	cache := overflow(DropNewest)
+	if keys := received(cache); len(keys) != 2 || keys[0] != "key 0" || keys[1] != "key 1" {
+		t.Errorf("Expected DropNewest to keep the oldest events, got %v", keys)
+	}
+	if dropped := cache.DroppedEvents(); dropped != 2 {
+		t.Errorf("Expected 2 dropped events, got %d", dropped)
+	}
+	cache.Close()
+
+	cache = overflow(DropOldest)
+	if keys := received(cache); len(keys) != 2 || keys[0] != "key 2" || keys[1] != "key 3" {
+		t.Errorf("Expected DropOldest to keep the newest events, got %v", keys)
+	}
+	if dropped := cache.DroppedEvents(); dropped != 2 {
+		t.Errorf("Expected 2 dropped events, got %d", dropped)
+	}
+	cache.Close()
+
+	cache = overflow(Disabled)
+	if cache.Evictions != nil || cache.FinishedItems != nil {
+		t.Errorf("Expected Disabled to allocate no channels")
+	}
+	if dropped := cache.DroppedEvents(); dropped != 0 {
+		t.Errorf("Expected nothing to be sent nor dropped, %d dropped", dropped)
+	}
+	cache.Close()
+}
+
+func TestOverflowBlock(t *testing.T) {
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Length: 1, Overflow: Block})
+	deleted := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			cache.Set(fmt.Sprintf("key %d", i), "value")
+			cache.Delete(fmt.Sprintf("key %d", i))
+		}
+		close(deleted)
+	}()
+
+	<-time.After(20 * time.Millisecond)
+	if data, found := cache.Get("missing"); found {
+		t.Errorf("Expected the cache to keep serving while a send blocks, got %s", data)
+	}
+	for i := 0; i < 3; i++ {
+		if event := <-cache.Evictions; event.Key != fmt.Sprintf("key %d", i) {
+			t.Errorf("Expected every event in order, got %s", event.Key)
+		}
+	}
+	<-deleted
+	if dropped := cache.DroppedEvents(); dropped != 0 {
+		t.Errorf("Expected Block to drop nothing, %d dropped", dropped)
+	}
+
+	cache.Set("blocked", "value")
+	cache.Delete("blocked")
+	go func() {
+		cache.Set("other", "value")
+		cache.Delete("other")
+	}()
+	<-time.After(20 * time.Millisecond)
+	closed := make(chan struct{})
+	go func() {
+		cache.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected Close to give up on blocked sends")
+	}
+}
+
+func TestRename(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	var events []EvictionEvent[string, string]
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		events = append(events, EvictionEvent[string, string]{Key: key, Data: data, Reason: reason})
+	}
+
+	cache.SetWithTTL("staging", "new", time.Minute)
+	cache.Set("live", "old")
+	clock.Advance(10 * time.Second)
+	if !cache.Rename("staging", "live") {
+		t.Errorf("Expected a live item to be renamed")
+	}
+	if cache.Has("staging") {
+		t.Errorf("Expected the item to leave its old key")
+	}
+	if data, found := cache.Peek("live"); !found || data != "new" {
+		t.Errorf("Expected the item to move to its new key, got %s", data)
+	}
+	if remaining, _ := cache.TTLRemaining("live"); remaining != 50*time.Second {
+		t.Errorf("Expected the item to keep its remaining ttl, got %s", remaining)
+	}
+	if len(events) != 1 || events[0].Key != "live" || events[0].Data != "old" || events[0].Reason != Expired {
+		t.Errorf("Expected only the clobbered item to be reported, got %+v", events)
+	}
+
+	cache.Set("other", "value")
+	events = nil
+	if !cache.Rename("live", "other") || len(events) != 1 || events[0].Reason != Replaced {
+		t.Errorf("Expected a live destination to be reported as replaced, got %+v", events)
+	}
+	if cache.Rename("missing", "other") {
+		t.Errorf("Expected a missing item to not be renamed")
+	}
+	cache.Set("expired", "value")
+	clock.Advance(2 * time.Second)
+	if cache.Rename("expired", "other") {
+		t.Errorf("Expected an expired item to not be renamed")
+	}
+	if data, found := cache.Peek("other"); !found || data != "new" {
+		t.Errorf("Expected a failed rename to leave the destination alone")
+	}
+	if cache.Count() != 2 {
+		t.Errorf("Expected 2 items, got %d", cache.Count())
+	}
+}
+
+func TestRenameCopiesItem(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	cache.SetWithWeight("old", "value", 7)
+	cache.Get("old")
+
+	item := cache.items["old"]
+	version := item.version
+	cache.Rename("old", "new")
+	if cache.items["new"] == item || item.version != version {
+		t.Errorf("Expected the renamed item to be stored as a copy, leaving the original as it was")
+	}
+	if info, _ := cache.GetEntry("new"); info.AccessCount != 2 || cache.SizeBytes() != 7 {
+		t.Errorf("Expected the copy to keep the weight and access count of the item, got %d, %d", info.AccessCount, cache.SizeBytes())
+	}
+}
+
+func TestPauseCleanup(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+
+	cache.PauseCleanup()
+	cache.Set("short", "lived")
+	clock.Advance(2 * time.Second)
+	<-time.After(20 * time.Millisecond)
+	select {
+	case event := <-cache.Evictions:
+		t.Errorf("Expected no eviction while paused, got %+v", event)
+	default:
+	}
+	if _, found := cache.Get("short"); found {
+		t.Errorf("Expected the expired item to miss while paused")
+	}
+	if cache.Count() != 1 {
+		t.Errorf("Expected the expired item to stay until resumed")
+	}
+
+	cache.ResumeCleanup()
+	select {
+	case event := <-cache.Evictions:
+		if event.Key != "short" || event.Reason != Expired {
+			t.Errorf("Expected `short` to be swept on resume, got %+v", event)
+		}
+	default:
+		t.Errorf("Expected resuming to sweep the items that expired")
+	}
+	if cache.Count() != 0 {
+		t.Errorf("Expected no items left, got %d", cache.Count())
+	}
+}
+
+func TestCleanupDone(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock, CleanupDone: true})
+	other := NewCache(time.Second)
+	defer other.Close()
+	if other.CleanupDone != nil {
+		t.Errorf("Expected CleanupDone to not be allocated by default")
+	}
+
+	for _, count := range []int{2, 0, 1} {
+		for i := 0; i < count; i++ {
+			cache.Set(fmt.Sprintf("key %d", i), "value")
+		}
+		clock.Advance(1100 * time.Millisecond)
+		select {
+		case removed := <-cache.CleanupDone:
+			if removed != count {
+				t.Errorf("Expected the sweep to report %d removed items, got %d", count, removed)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected a tick of CleanupDone every interval")
+		}
+	}
+
+	cache.Close()
+	if _, open := <-cache.CleanupDone; open {
+		t.Errorf("Expected CleanupDone to be closed")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	cache.Set("hello", "world")
+	deadline, _ := cache.ExpiresAt("hello")
+	clock.Advance(600 * time.Millisecond)
+	if !cache.Update("hello", "there") {
+		t.Errorf("Expected a live item to be updated")
+	}
+	if data, _ := cache.Peek("hello"); data != "there" {
+		t.Errorf("Expected the updated data, got %s", data)
+	}
+	if expiresAt, _ := cache.ExpiresAt("hello"); !expiresAt.Equal(deadline) {
+		t.Errorf("Expected the item to keep its deadline of %s, got %s", deadline, expiresAt)
+	}
+	clock.Advance(500 * time.Millisecond)
+	if cache.Has("hello") {
+		t.Errorf("Expected the item to expire at its original deadline")
+	}
+	if cache.Update("hello", "again") || cache.Update("missing", "value") {
+		t.Errorf("Expected expired and missing items to not be updated")
+	}
+}
+
+func TestUpdateKeepsAccountingAndWeight(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+
+	cache.SetWithWeight("hello", "world", 100)
+	cache.Get("hello")
+	cache.Get("hello")
+	cache.Update("hello", "there")
+	if info, _ := cache.GetEntry("hello"); info.AccessCount != 3 {
+		t.Errorf("Expected the access count to carry over the update, got %d", info.AccessCount)
+	}
+	if size := cache.SizeBytes(); size != 100 {
+		t.Errorf("Expected the weight to carry over the update, got %d bytes", size)
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	for _, key := range []string{"delta", "alpha", "charlie", "echo", "bravo"} {
+		cache.Set(key, strings.ToUpper(key))
+	}
+
+	expected := []string{"alpha", "bravo", "charlie", "delta", "echo"}
+	for i := 0; i < 5; i++ {
+		if keys := cache.SortedKeys(); strings.Join(keys, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected sorted keys %v, got %v", expected, keys)
+		}
+		var visited []string
+		cache.RangeSorted(func(key string, data string) bool {
+			if data != strings.ToUpper(key) {
+				t.Errorf("Expected the data of `%s`, got %s", key, data)
+			}
+			visited = append(visited, key)
+			return len(visited) < 3
+		})
+		if strings.Join(visited, ",") != "alpha,bravo,charlie" {
+			t.Errorf("Expected RangeSorted to visit in order and stop early, got %v", visited)
+		}
+	}
+
+	ints := New[int, string](time.Second)
+	defer ints.Close()
+	for _, key := range []int{3, 10, 1} {
+		ints.Set(key, "value")
+	}
+	if keys := ints.SortedKeys(); fmt.Sprint(keys) != "[1 10 3]" {
+		t.Errorf("Expected keys that are not strings to sort by their text, got %v", keys)
+	}
+}
+
+func TestInitialCapacity(t *testing.T) {
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, InitialCapacity: 100})
+	defer cache.Close()
+
+	for i := 0; i < 200; i++ {
+		cache.Set(fmt.Sprintf("key %d", i), fmt.Sprintf("value %d", i))
+	}
+	for i := 0; i < 200; i++ {
+		if data, found := cache.Get(fmt.Sprintf("key %d", i)); !found || data != fmt.Sprintf("value %d", i) {
+			t.Errorf("Expected `key %d` beyond the initial capacity to be found", i)
+		}
+	}
+	cache.Flush()
+	cache.Set("hello", "world")
+	if cache.Count() != 1 {
+		t.Errorf("Expected the flushed cache to hold 1 item, got %d", cache.Count())
+	}
+}
+
+func TestOnExpire(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	var order, expired []string
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		order = append(order, "evicted "+key)
+	}
+	cache.OnExpire = func(key string, data string) {
+		order = append(order, "expire "+key)
+		expired = append(expired, key+"="+data)
+	}
+
+	cache.Set("deleted", "1")
+	cache.Set("expired", "2")
+	cache.Delete("deleted")
+	clock.Advance(2 * time.Second)
+	cache.Cleanup()
+
+	if len(expired) != 1 || expired[0] != "expired=2" {
+		t.Errorf("Expected OnExpire only for the expired item, got %v", expired)
+	}
+	if strings.Join(order, ",") != "evicted deleted,evicted expired,expire expired" {
+		t.Errorf("Expected OnEvicted to be invoked before OnExpire, got %v", order)
+	}
+}
+
+func TestTouchMany(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+	cache.SetWithTTL("expired", "3", 100*time.Millisecond)
+	clock.Advance(800 * time.Millisecond)
+	if touched := cache.TouchMany([]string{"a", "b", "expired", "missing"}); touched != 2 {
+		t.Errorf("Expected 2 live items to be touched, got %d", touched)
+	}
+	clock.Advance(800 * time.Millisecond)
+	if !cache.Has("a") || !cache.Has("b") {
+		t.Errorf("Expected the touched items to be extended")
+	}
+	if cache.Has("expired") || cache.Has("missing") {
+		t.Errorf("Expected skipped items to not be resurrected")
+	}
+}
+
+func TestGetOrReap(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	cache.Set("hello", "world")
+	if data, found := cache.GetOrReap("hello"); !found || data != "world" {
+		t.Errorf("Expected GetOrReap to find a live item")
+	}
+	clock.Advance(2 * time.Second)
+	if _, found := cache.GetOrReap("hello"); found {
+		t.Errorf("Expected the expired item to miss")
+	}
+	if cache.Count() != 0 {
+		t.Errorf("Expected the expired item to be reaped, %d items left", cache.Count())
+	}
+	select {
+	case event := <-cache.Evictions:
+		if event.Key != "hello" || event.Reason != Expired {
+			t.Errorf("Expected an expiry event for `hello`, got %+v", event)
+		}
+	default:
+		t.Errorf("Expected the reaped item to be reported right away")
+	}
+	if data := <-cache.FinishedItems; data != "world" {
+		t.Errorf("Expected the reaped data on FinishedItems, got %s", data)
+	}
+	if removed := cache.Cleanup(); removed != 0 {
+		t.Errorf("Expected nothing left for the sweep, %d removed", removed)
+	}
+}
+
+func TestSetWithDeadline(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	deadline := clock.Now().Add(time.Minute)
+	cache.SetWithDeadline("future", "value", deadline)
+	cache.SetWithDeadline("past", "value", clock.Now().Add(-time.Second))
+	if expiresAt, _ := cache.ExpiresAt("future"); !expiresAt.Equal(deadline) {
+		t.Errorf("Expected the item to expire at its deadline, got %s", expiresAt)
+	}
+	if _, found := cache.Get("past"); found {
+		t.Errorf("Expected an item past its deadline to be expired")
+	}
+
+	clock.Advance(59 * time.Second)
+	cache.Get("future")
+	if expiresAt, _ := cache.ExpiresAt("future"); !expiresAt.Equal(deadline) {
+		t.Errorf("Expected lookups to not extend the deadline, got %s", expiresAt)
+	}
+	clock.Advance(2 * time.Second)
+	if removed := cache.Cleanup(); removed != 2 || cache.Count() != 0 {
+		t.Errorf("Expected both items to be swept once past their deadline, %d removed", removed)
+	}
+}
+
+func TestSetWithDeadlineKeptByUpdates(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	deadline := clock.Now().Add(time.Minute)
+	updates := map[string]func(key string){
+		"replace": func(key string) { cache.Replace(key, "2") },
+		"swap":    func(key string) { cache.CompareAndSwap(key, "1", "2") },
+		"version": func(key string) {
+			_, version, _ := cache.GetVersioned(key)
+			cache.CompareVersionAndSwap(key, version, "2")
+		},
+		"increment": func(key string) { cache.Increment(key, 1) },
+	}
+	for key, update := range updates {
+		cache.SetWithDeadline(key, "1", deadline)
+		update(key)
+		if data, _ := cache.Peek(key); data != "2" {
+			t.Errorf("Expected %s to update the item, got %q", key, data)
+		}
+		if expiresAt, _ := cache.ExpiresAt(key); !expiresAt.Equal(deadline) {
+			t.Errorf("Expected %s to keep the deadline of the item, got %s", key, expiresAt)
+		}
+	}
+	clock.Advance(time.Minute + time.Second)
+	if removed := cache.Cleanup(); removed != len(updates) {
+		t.Errorf("Expected the updated items to expire at their deadline, %d removed", removed)
+	}
+}
+
+func TestRenewWhenNear(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.SetTouchStrategy(RenewWhenNear(0.5))
+
+	cache.Set("hello", "world")
+	deadline, _ := cache.ExpiresAt("hello")
+	clock.Advance(200 * time.Millisecond)
+	cache.Get("hello")
+	if expiresAt, _ := cache.ExpiresAt("hello"); !expiresAt.Equal(deadline) {
+		t.Errorf("Expected a lookup with plenty of ttl left to not renew the item")
+	}
+	clock.Advance(400 * time.Millisecond)
+	cache.Get("hello")
+	if expiresAt, _ := cache.ExpiresAt("hello"); !expiresAt.Equal(clock.Now().Add(time.Second)) {
+		t.Errorf("Expected a lookup near expiry to renew the item, expires at %s", expiresAt.Sub(deadline))
+	}
+
+	cache.SetTouchStrategy(RenewAlways)
+	clock.Advance(100 * time.Millisecond)
+	cache.Get("hello")
+	if expiresAt, _ := cache.ExpiresAt("hello"); !expiresAt.Equal(clock.Now().Add(time.Second)) {
+		t.Errorf("Expected RenewAlways to renew the item on every lookup")
+	}
+}
+
+func benchmarkTouchStrategy(b *testing.B, strategy TouchStrategy) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	cache.SetTouchStrategy(strategy)
+	cache.Set("hello", "world")
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			cache.Get("hello")
+		}
+	})
+}
+
+func BenchmarkGetRenewAlways(b *testing.B) {
+	benchmarkTouchStrategy(b, RenewAlways)
+}
+
+func BenchmarkGetRenewWhenNear(b *testing.B) {
+	benchmarkTouchStrategy(b, RenewWhenNear(0.5))
+}
+
+func TestConcurrentItemAccess(t *testing.T) {
+	for _, bounded := range []bool{false, true} {
+		cache := NewCache(5 * time.Millisecond)
+		cache.SetCleanupInterval(time.Millisecond)
+		if bounded {
+			cache.SetMaxItems(50)
+		}
+		cache.SetTouchStrategy(RenewWhenNear(0.5))
+
+		var wg sync.WaitGroup
+		stop := make(chan struct{})
+		hammer := func(f func(key string)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; ; i++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					f(fmt.Sprintf("key %d", i%100))
+				}
+			}()
+		}
+		for i := 0; i < 2; i++ {
+			hammer(func(key string) { cache.Set(key, "value") })
+			hammer(func(key string) { cache.Get(key) })
+			hammer(func(key string) { cache.Peek(key) })
+			hammer(func(key string) { cache.GetWithExpiration(key) })
+			hammer(func(key string) { cache.Touch(key) })
+			hammer(func(key string) { cache.Update(key, "updated") })
+			hammer(func(string) { cache.Cleanup() })
+		}
+		hammer(func(string) { cache.Entries() })
+		hammer(func(string) { cache.Export() })
+		<-time.After(100 * time.Millisecond)
+		close(stop)
+		wg.Wait()
+		cache.Close()
+	}
+}
+
+func TestGetManyWithExpiration(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	cache.Set("default", "1")
+	cache.SetWithTTL("minute", "2", time.Minute)
+	cache.SetWithTTL("forever", "3", -1)
+	cache.SetWithTTL("expired", "4", 100*time.Millisecond)
+	clock.Advance(500 * time.Millisecond)
+
+	now := clock.Now()
+	result := cache.GetManyWithExpiration([]string{"default", "minute", "forever", "expired", "missing"})
+	expected := map[string]ItemInfo[string]{
+		"default": {Data: "1", ExpiresAt: now.Add(time.Second), AccessCount: 1},
+		"minute":  {Data: "2", ExpiresAt: now.Add(time.Minute), AccessCount: 1},
+		"forever": {Data: "3", AccessCount: 1},
+	}
+	if len(result) != len(expected) {
+		t.Errorf("Expected only the live items, got %v", result)
+	}
+	for key, want := range expected {
+		if got := result[key]; got.Data != want.Data || !got.ExpiresAt.Equal(want.ExpiresAt) || got.AccessCount != want.AccessCount {
+			t.Errorf("Expected %+v for `%s`, got %+v", want, key, got)
+		}
+	}
+	if stats := cache.Stats(); stats.Hits != 3 || stats.Misses != 2 {
+		t.Errorf("Expected 3 hits and 2 misses, got %+v", stats)
+	}
+
+	cache.SetSlidingExpiration(false)
+	clock.Advance(500 * time.Millisecond)
+	if got := cache.GetManyWithExpiration([]string{"default"})["default"]; !got.ExpiresAt.Equal(now.Add(time.Second)) {
+		t.Errorf("Expected the lookup to not touch the item without sliding expiration, got %s", got.ExpiresAt)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Minute, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.Merge = func(existing, incoming string) string {
+		return existing + "\n" + incoming
+	}
+
+	cache.Set("log", "first")
+	clock.Advance(30 * time.Second)
+	cache.Set("log", "second")
+	if data, _ := cache.Peek("log"); data != "first\nsecond" {
+		t.Errorf("Expected both writes to be combined, got %q", data)
+	}
+	if expiresAt, _ := cache.ExpiresAt("log"); !expiresAt.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("Expected the merged item to get a fresh ttl, got %s", expiresAt)
+	}
+	cache.SetMany(map[string]string{"log": "third"})
+	if data, _ := cache.Peek("log"); data != "first\nsecond\nthird" {
+		t.Errorf("Expected SetMany to merge as well, got %q", data)
+	}
+
+	clock.Advance(2 * time.Minute)
+	cache.Set("log", "fresh")
+	if data, _ := cache.Peek("log"); data != "fresh" {
+		t.Errorf("Expected an expired item to be replaced, got %q", data)
+	}
+
+	cache.Merge = func(existing, incoming string) string {
+		return existing + incoming
+	}
+	cache.Set("concurrent", "")
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Set("concurrent", "x")
+		}()
+	}
+	wg.Wait()
+	if data, _ := cache.Peek("concurrent"); data != strings.Repeat("x", 50) {
+		t.Errorf("Expected every concurrent write to be merged, got %d of 50", len(data))
+	}
+}
+
+func TestOnPanic(t *testing.T) {
+	cache := NewCache(time.Millisecond)
+	defer cache.Close()
+	panics := make(chan interface{}, 10)
+	cache.OnPanic = func(r interface{}) {
+		panics <- r
+	}
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		panic("evicted " + key)
+	}
+	cache.SetCleanupInterval(10 * time.Millisecond)
+
+	for _, key := range []string{"a", "b"} {
+		cache.Set(key, "value")
+		select {
+		case r := <-panics:
+			if r != "evicted "+key {
+				t.Errorf("Expected the panic of `%s` to be recovered, got %v", key, r)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected `%s` to be swept despite the previous panic", key)
+		}
+	}
+	if !cache.CleanerRunning() {
+		t.Errorf("Expected the cleaner to keep running after a panic")
+	}
+	if count := cache.Count(); count != 0 {
+		t.Errorf("Expected the panicking sweeps to remove their items, got %d", count)
+	}
+}
+
+func TestGetEntry(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Minute, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	created := clock.Now()
+	cache.Set("key", "value")
+	clock.Advance(10 * time.Second)
+	cache.Get("key")
+	clock.Advance(10 * time.Second)
+	info, found := cache.GetEntry("key")
+	if !found || info.Data != "value" {
+		t.Fatalf("Expected the entry to be found, got %+v, %v", info, found)
+	}
+	if !info.CreatedAt.Equal(created) {
+		t.Errorf("Expected the entry to be created at %s, got %s", created, info.CreatedAt)
+	}
+	if want := clock.Now().Add(time.Minute); !info.ExpiresAt.Equal(want) {
+		t.Errorf("Expected the lookup to extend the entry to %s, got %s", want, info.ExpiresAt)
+	}
+	if info.AccessCount != 2 {
+		t.Errorf("Expected the entry to count both lookups, got %d", info.AccessCount)
+	}
+	if stats := cache.Stats(); stats.Hits != 2 {
+		t.Errorf("Expected GetEntry to count as a hit, got %d", stats.Hits)
+	}
+
+	cache.SetSlidingExpiration(false)
+	clock.Advance(10 * time.Second)
+	if info, _ := cache.GetEntry("key"); !info.ExpiresAt.Equal(clock.Now().Add(50*time.Second)) || info.AccessCount != 3 {
+		t.Errorf("Expected the entry to not be extended without sliding expiration, got %+v", info)
+	}
+
+	if _, found := cache.GetEntry("missing"); found {
+		t.Errorf("Expected a missing key to not be found")
+	}
+}
+
+func TestNewCacheWithConfig(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		cfg  Config
+	}{
+		{"zero ttl", Config{}},
+		{"negative length", Config{TTL: time.Second, Length: -1}},
+		{"negative dispatch buffer", Config{TTL: time.Second, DispatchBuffer: -1}},
+		{"negative initial capacity", Config{TTL: time.Second, InitialCapacity: -1}},
+		{"negative max items", Config{TTL: time.Second, MaxItems: -1}},
+		{"negative max bytes", Config{TTL: time.Second, MaxBytes: -1}},
+		{"unknown overflow policy", Config{TTL: time.Second, Overflow: Disabled + 1}},
+		{"length without channels", Config{TTL: time.Second, Length: 10, Overflow: Disabled}},
+	} {
+		cache, err := NewCacheWithConfig(test.cfg)
+		if !errors.Is(err, ErrInvalidConfig) || cache != nil {
+			t.Errorf("Expected %s to be rejected, got %v", test.name, err)
+		}
+	}
+
+	cache, err := NewCacheWithConfig(Config{TTL: time.Second, Length: 10, Overflow: Disabled, CleanupDone: true, MaxItems: 2})
+	if err != nil {
+		t.Fatalf("Expected a valid config to be accepted, got %v", err)
+	}
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+	cache.Set("c", "3")
+	if count := cache.Count(); count != 2 || cache.Has("a") {
+		t.Errorf("Expected MaxItems to cap the cache, got %v", cache.Keys())
+	}
+	cache.Close()
+
+	clock := NewFakeClock(time.Now())
+	cache, err = NewCacheWithConfig(Config{TTL: -1, Clock: clock})
+	if err != nil {
+		t.Fatalf("Expected a negative ttl to be accepted, got %v", err)
+	}
+	defer cache.Close()
+	cache.Set("forever", "value")
+	cache.SetWithTTL("short", "value", time.Minute)
+	clock.Advance(time.Hour)
+	if _, exists := cache.Get("forever"); !exists {
+		t.Errorf("Expected items stored with a negative default ttl to never expire")
+	}
+	if _, exists := cache.Get("short"); exists {
+		t.Errorf("Expected items with their own ttl to expire")
+	}
+}
+
+func TestSetNegativeTTL(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Minute, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	cache.Set("before", "value")
+	cache.SetTTL(-1)
+	for i := 0; i < 2; i++ {
+		if _, exists := cache.Get("before"); !exists {
+			t.Errorf("Expected `before` to be live on lookup %d after a negative ttl", i+1)
+		}
+	}
+	if expiresAt, _ := cache.ExpiresAt("before"); !expiresAt.IsZero() {
+		t.Errorf("Expected a touched `before` to never expire, got %s", expiresAt)
+	}
+	clock.Advance(time.Hour)
+	if removed := cache.Cleanup(); removed != 0 {
+		t.Errorf("Expected nothing to be swept, %d removed", removed)
+	}
+	if _, exists := cache.Get("before"); !exists {
+		t.Errorf("Expected `before` to never expire once touched")
+	}
+}
+
+func TestTransform(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Minute, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	cache.Set("a", "apple")
+	cache.Set("b", "banana")
+	cache.SetWithTTL("c", "cherry", time.Hour)
+	cache.SetWithTTL("expired", "fig", time.Second)
+	clock.Advance(10 * time.Second)
+	deadline, _ := cache.ExpiresAt("c")
+	var events []string
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		events = append(events, key+" "+reason.String())
+	}
+
+	deleted := cache.Transform(func(key string, data string) (string, bool) {
+		if key == "expired" {
+			t.Errorf("Expected expired items to be skipped")
+		}
+		return strings.ToUpper(data), !strings.HasPrefix(data, "b")
+	})
+	if deleted != 1 {
+		t.Errorf("Expected one item to be deleted, got %d", deleted)
+	}
+	if snapshot := cache.Snapshot(); len(snapshot) != 2 || snapshot["a"] != "APPLE" || snapshot["c"] != "CHERRY" {
+		t.Errorf("Expected the kept values to be uppercased, got %v", snapshot)
+	}
+	if expiresAt, _ := cache.ExpiresAt("c"); !expiresAt.Equal(deadline) {
+		t.Errorf("Expected the deadline to be preserved, got %s instead of %s", expiresAt, deadline)
+	}
+	sort.Strings(events)
+	if expected := []string{"a replaced", "b deleted", "c replaced"}; fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("Expected events %v, got %v", expected, events)
+	}
+}
+
+func TestTransformKeepsEvictionOrder(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	cache.SetMaxItems(4)
+
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Set(key, key)
+	}
+	cache.SetWithWeight("d", "d", 10)
+	cache.Get("a")
+	cache.Transform(func(key string, data string) (string, bool) {
+		return strings.ToUpper(data), true
+	})
+	var evicted []string
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		evicted = append(evicted, key)
+	}
+	cache.Set("e", "e")
+	cache.Set("f", "f")
+	if expected := []string{"b", "c"}; fmt.Sprint(evicted) != fmt.Sprint(expected) {
+		t.Errorf("Expected Transform to keep the eviction order %v, got %v", expected, evicted)
+	}
+	if size := cache.SizeBytes(); size != 13 {
+		t.Errorf("Expected the rewritten items to keep their size and weight, got %d", size)
+	}
+	if info, _ := cache.GetEntry("a"); info.AccessCount != 2 {
+		t.Errorf("Expected the rewritten items to keep their access count, got %d", info.AccessCount)
+	}
+}
@@ -0,0 +1,121 @@
+package ttlcache
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time used by a cache to expire items and schedule sweeps
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at intervals, like a time.Ticker
+type Ticker interface {
+	Chan() <-chan time.Time
+	Reset(d time.Duration)
+	Stop()
+}
+
+// realClock is the Clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{ticker: time.NewTicker(d)}
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t realTicker) Chan() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t realTicker) Reset(d time.Duration) {
+	t.ticker.Reset(d)
+}
+
+func (t realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// FakeClock is a Clock that only moves when told to, for deterministic tests
+type FakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// NewFakeClock returns a FakeClock set to now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the current time of the clock
+func (clock *FakeClock) Now() time.Time {
+	clock.mutex.Lock()
+	now := clock.now
+	clock.mutex.Unlock()
+	return now
+}
+
+// Advance moves the clock forward, firing the tickers that fall due
+// Like a time.Ticker, a ticker that falls due several times fires only once
+func (clock *FakeClock) Advance(d time.Duration) {
+	clock.mutex.Lock()
+	clock.now = clock.now.Add(d)
+	for _, ticker := range clock.tickers {
+		if ticker.stopped || ticker.next.After(clock.now) {
+			continue
+		}
+		select {
+		case ticker.c <- clock.now:
+		default:
+		}
+		for !ticker.next.After(clock.now) {
+			ticker.next = ticker.next.Add(ticker.period)
+		}
+	}
+	clock.mutex.Unlock()
+}
+
+// NewTicker returns a Ticker firing as the clock is advanced
+func (clock *FakeClock) NewTicker(d time.Duration) Ticker {
+	clock.mutex.Lock()
+	ticker := &fakeTicker{clock: clock, c: make(chan time.Time, 1), period: d, next: clock.now.Add(d)}
+	clock.tickers = append(clock.tickers, ticker)
+	clock.mutex.Unlock()
+	return ticker
+}
+
+type fakeTicker struct {
+	clock   *FakeClock
+	c       chan time.Time
+	period  time.Duration
+	next    time.Time
+	stopped bool
+}
+
+func (t *fakeTicker) Chan() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	t.clock.mutex.Lock()
+	t.period = d
+	t.next = t.clock.now.Add(d)
+	t.stopped = false
+	t.clock.mutex.Unlock()
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mutex.Lock()
+	t.stopped = true
+	t.clock.mutex.Unlock()
+}
@@ -0,0 +1,47 @@
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClockExpiration(t *testing.T) {
+	clock := NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := NewWithConfig[string, string](Config{TTL: time.Minute, Clock: clock})
+	defer cache.Close()
+
+	cache.Set("hello", "world")
+	clock.Advance(30 * time.Second)
+	if _, exists := cache.Get("hello"); !exists {
+		t.Errorf("Expected `hello` to not have expired after 30s")
+	}
+	if remaining, _ := cache.TTLRemaining("hello"); remaining != time.Minute {
+		t.Errorf("Expected Get to extend `hello` by a minute, got %s remaining", remaining)
+	}
+
+	clock.Advance(61 * time.Second)
+	if _, exists := cache.Peek("hello"); exists {
+		t.Errorf("Expected `hello` to have expired after advancing the clock")
+	}
+}
+
+func TestFakeClockCleanup(t *testing.T) {
+	clock := NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := NewWithConfig[string, string](Config{TTL: time.Hour, Clock: clock})
+	defer cache.Close()
+
+	cache.Set("hello", "world")
+	clock.Advance(2 * time.Hour)
+
+	select {
+	case data := <-cache.FinishedItems:
+		if data != "world" {
+			t.Errorf("Expected `world` to be notified, got %s", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected advancing the clock to trigger a sweep")
+	}
+	if count := cache.Count(); count != 0 {
+		t.Errorf("Expected the sweep to empty the cache, got %d items", count)
+	}
+}
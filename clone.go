@@ -0,0 +1,111 @@
+package ttlcache
+
+// Clone returns a new, independent cache with the settings of the cache and a
+// copy of its live items, which keep their deadlines, weights and tags. Byte slice
+// data is copied, so the clone shares nothing with the cache. The clone has its
+// own cleanup goroutine, notification channels and stats, which start empty
+// The hooks shaping stored items, KeyFunc, TTLFunc, Sizer, Merge and CanEvict,
+// carry over, while the notification callbacks and the Observer do not. The LFU
+// policy of the clone starts over with fresh counts, and a custom eviction policy
+// cannot be copied, the clone then uses the default LRU policy
+func (cache *Cache[K, V]) Clone() *Cache[K, V] {
+	type entry struct {
+		key  K
+		item *Item[V]
+	}
+	cache.mutex.RLock()
+	clone := NewWithConfig[K, V](Config{
+		TTL:             cache.ttl,
+		Length:          cache.Length,
+		Clock:           cache.clock,
+		DispatchBuffer:  cap(cache.callbacks),
+		Overflow:        cache.overflow,
+		InitialCapacity: cache.capacity,
+		CleanupDone:     cache.cleanupDone != nil,
+		NoCleanup:       cache.ticker == nil,
+	})
+	clone.KeyFunc = cache.KeyFunc
+	clone.TTLFunc = cache.TTLFunc
+	clone.Sizer = cache.Sizer
+	clone.Merge = cache.Merge
+	clone.CanEvict = cache.CanEvict
+	switch policy := cache.policy.(type) {
+	case *listPolicy[K]:
+		clone.policy = &listPolicy[K]{promote: policy.promote}
+	case *lfuPolicy[K]:
+		clone.policy = &lfuPolicy[K]{decay: policy.decay, clock: policy.clock}
+	}
+	clone.noSliding = cache.noSliding
+	clone.strictExpiry = cache.strictExpiry
+	clone.maxTTL = cache.maxTTL
+	clone.maxLifetime = cache.maxLifetime
+	clone.maxValueSize = cache.maxValueSize
+	clone.renewNear = cache.renewNear
+	clone.jitter = cache.jitter
+	clone.slowCleanup = cache.slowCleanup
+	clone.keysOnly = cache.keysOnly
+	clone.maxItems = cache.maxItems
+	clone.maxBytes = cache.maxBytes
+	clone.interval = cache.interval
+	clone.minInterval = cache.minInterval
+	clone.paused = cache.paused
+	clone.cleanupBatch = cache.cleanupBatch
+	clone.expireAfterWrite = cache.expireAfterWrite
+	clone.expireAfterAccess = cache.expireAfterAccess
+	clone.loader = cache.loader
+	clone.fallback = cache.fallback
+	clone.refreshThreshold = cache.refreshThreshold
+	clone.refreshLoader = cache.refreshLoader
+	cache.refreshMutex.Lock()
+	clone.maxRefreshes = cache.maxRefreshes
+	cache.refreshMutex.Unlock()
+	clone.negativeTTL = cache.negativeTTL
+	clone.errorTTL = cache.errorTTL
+	clone.copyOnStore = cache.copyOnStore
+	clone.copyOnGet = cache.copyOnGet
+	clone.trackMisses = cache.trackMisses
+	cache.missed.Lock()
+	clone.missed.max = cache.missed.max
+	cache.missed.Unlock()
+
+	// copy the items from the least recently used, so that the clone evicts them in the same order
+	var entries []entry
+	add := func(key K) {
+		item, exists := cache.items[key]
+		if !exists || cache.expired(item) {
+			return
+		}
+		item.RLock()
+		expires := item.expires
+		item.RUnlock()
+		entries = append(entries, entry{key, &Item[V]{
+			data:    cloneValue(item.data),
+			ttl:     item.ttl,
+			expires: expires,
+			weight:  item.weight,
+			jitter:  item.jitter,
+			written: item.written,
+			fixed:   item.fixed,
+			created: item.created,
+			tags:    item.tags,
+		}})
+	}
+	if policy, ok := cache.policy.(*listPolicy[K]); ok && len(policy.elements) == len(cache.items) {
+		for element := policy.order.Back(); element != nil; element = element.Prev() {
+			add(element.Value.(K))
+		}
+	} else {
+		for key := range cache.items {
+			add(key)
+		}
+	}
+	cache.mutex.RUnlock()
+
+	clone.mutex.Lock()
+	clone.reschedule()
+	for _, e := range entries {
+		clone.insert(e.key, e.item)
+	}
+	clone.mutex.Unlock()
+	return clone
+}
@@ -0,0 +1,67 @@
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClone(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, []byte](Config{TTL: time.Minute, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.SetMaxItems(4)
+
+	cache.Set("a", []byte("a"))
+	cache.SetWithTTL("forever", []byte("forever"), -1)
+	cache.SetWithTTL("expired", []byte("expired"), time.Second)
+	clock.Advance(10 * time.Second)
+	cache.Set("b", []byte("b"))
+	cache.Get("a")
+
+	clone := cache.Clone()
+	defer clone.Close()
+	if count := clone.Count(); count != 3 {
+		t.Errorf("Expected the 3 live items to be cloned, got %d", count)
+	}
+	if clone.Has("expired") {
+		t.Errorf("Expected the expired item to be left out")
+	}
+	for _, key := range []string{"a", "forever", "b"} {
+		original, _ := cache.ExpiresAt(key)
+		if cloned, _ := clone.ExpiresAt(key); !cloned.Equal(original) {
+			t.Errorf("Expected `%s` to keep its deadline %s, got %s", key, original, cloned)
+		}
+	}
+	if ttl, _ := clone.TTLRemaining("b"); ttl != time.Minute {
+		t.Errorf("Expected the remaining ttl of `b` to carry over, got %s", ttl)
+	}
+
+	data, _ := clone.Peek("a")
+	data[0] = 'x'
+	clone.Set("c", []byte("c"))
+	clone.Delete("b")
+	if data, _ := cache.Peek("a"); string(data) != "a" {
+		t.Errorf("Expected the data of the clone to be a copy, got %s", data)
+	}
+	if cache.Has("c") || !cache.Has("b") {
+		t.Errorf("Expected the mutations of the clone to not affect the original")
+	}
+	cache.Delete("a")
+	if !clone.Has("a") {
+		t.Errorf("Expected the mutations of the original to not affect the clone")
+	}
+
+	// the cap and the recency order carry over, `forever` being the least recently used
+	clone.Set("d", []byte("d"))
+	clone.Set("e", []byte("e"))
+	if clone.Has("forever") || clone.Count() != 4 {
+		t.Errorf("Expected the clone to evict its least recently used item, got %v", clone.Keys())
+	}
+	closed := clone.Clone()
+	clone.Close()
+	if !closed.Has("a") || closed.Closed() {
+		t.Errorf("Expected a clone to outlive the cache it was cloned from")
+	}
+	closed.Close()
+}
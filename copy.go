@@ -0,0 +1,39 @@
+package ttlcache
+
+// SetCopyOnStore makes the cache keep its own copy of byte slice values, so
+// that callers mutating a slice after storing it do not corrupt the cached value
+// Copying costs an allocation per store, so it is disabled by default.
+// It has no effect on values of other types
+func (cache *Cache[K, V]) SetCopyOnStore(copy bool) {
+	cache.mutex.Lock()
+	cache.copyOnStore = copy
+	cache.mutex.Unlock()
+}
+
+// SetCopyOnGet makes lookups return a copy of byte slice values, so that
+// callers mutating a returned slice do not corrupt the cached value. It covers
+// every method handing out data, such as Range, Export and GetAndSet
+// Copying costs an allocation per lookup, so it is disabled by default.
+// It has no effect on values of other types
+func (cache *Cache[K, V]) SetCopyOnGet(copy bool) {
+	cache.mutex.Lock()
+	cache.copyOnGet = copy
+	cache.mutex.Unlock()
+}
+
+// loaned returns data as handed out by a lookup,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) loaned(data V) V {
+	if cache.copyOnGet {
+		return cloneValue(data)
+	}
+	return data
+}
+
+// cloneValue returns a copy of byte slice data, and any other data as is
+func cloneValue[V any](data V) V {
+	if b, ok := any(data).([]byte); ok && b != nil {
+		return any(append([]byte{}, b...)).(V)
+	}
+	return data
+}
@@ -0,0 +1,72 @@
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCopyOnStore(t *testing.T) {
+	cache := New[string, []byte](time.Second)
+	defer cache.Close()
+
+	shared := []byte("hello")
+	cache.Set("aliased", shared)
+	shared[0] = 'j'
+	if data, _ := cache.Get("aliased"); string(data) != "jello" {
+		t.Errorf("Expected the cache to share the slice by default, got %s", data)
+	}
+
+	cache.SetCopyOnStore(true)
+	value := []byte("hello")
+	cache.Set("copied", value)
+	value[0] = 'j'
+	if data, _ := cache.Get("copied"); string(data) != "hello" {
+		t.Errorf("Expected the cached value to be unchanged, got %s", data)
+	}
+}
+
+func TestCopyOnGet(t *testing.T) {
+	cache := New[string, []byte](time.Second)
+	defer cache.Close()
+	cache.SetCopyOnGet(true)
+
+	cache.Set("hello", []byte("hello"))
+	data, _ := cache.Get("hello")
+	data[0] = 'j'
+	if data, _ := cache.Peek("hello"); string(data) != "hello" {
+		t.Errorf("Expected the cached value to be unchanged, got %s", data)
+	}
+}
+
+func TestCopyOnGetEverywhere(t *testing.T) {
+	cache := New[string, []byte](time.Minute)
+	defer cache.Close()
+	cache.SetCopyOnGet(true)
+
+	cache.Set("hello", []byte("hello"))
+	cache.RangeSorted(func(key string, data []byte) bool {
+		data[0] = 'j'
+		return true
+	})
+	cache.Range(func(key string, data []byte) bool {
+		data[0] = 'j'
+		return true
+	})
+	for _, entry := range cache.Export() {
+		entry.Data[0] = 'j'
+	}
+	if data, _ := cache.Peek("hello"); string(data) != "hello" {
+		t.Errorf("Expected the cached value to be unchanged, got %s", data)
+	}
+
+	stored := []byte("value")
+	cache.Set("swapped", stored)
+	if old, _ := cache.GetAndSet("swapped", []byte("other")); &old[0] == &stored[0] {
+		t.Errorf("Expected GetAndSet to return a copy of the old value")
+	}
+	stored = []byte("value")
+	cache.Set("deleted", stored)
+	if old, _ := cache.GetAndDelete("deleted"); &old[0] == &stored[0] {
+		t.Errorf("Expected GetAndDelete to return a copy of the value")
+	}
+}
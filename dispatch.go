@@ -0,0 +1,49 @@
+package ttlcache
+
+import "sync/atomic"
+
+// dispatch runs f, the invocation of a callback, on the dispatch goroutine if
+// the cache was created with a DispatchBuffer, or right away otherwise
+// Callbacks that find the dispatch queue full are dropped and counted
+func (cache *Cache[K, V]) dispatch(f func()) {
+	if cache.callbacks == nil {
+		f()
+		return
+	}
+	cache.mutex.RLock()
+	if !cache.dispatched {
+		select {
+		case cache.callbacks <- f:
+		default:
+			atomic.AddInt64(&cache.droppedCallbacks, 1)
+		}
+	}
+	cache.mutex.RUnlock()
+}
+
+// DroppedCallbacks returns the number of callbacks that were not invoked
+// because the dispatch queue was full
+func (cache *Cache[K, V]) DroppedCallbacks() int64 {
+	return atomic.LoadInt64(&cache.droppedCallbacks)
+}
+
+// safely runs f on a background goroutine of the cache, recovering from a panic
+// and handing it to OnPanic so that the goroutine keeps running
+func (cache *Cache[K, V]) safely(f func()) {
+	defer func() {
+		if r := recover(); r != nil && cache.OnPanic != nil {
+			cache.OnPanic(r)
+		}
+	}()
+	f()
+}
+
+// startDispatcher runs the queued callbacks in order until the queue is closed
+func (cache *Cache[K, V]) startDispatcher(buffer int) {
+	cache.callbacks = make(chan func(), buffer)
+	go (func() {
+		for f := range cache.callbacks {
+			cache.safely(f)
+		}
+	})()
+}
@@ -0,0 +1,96 @@
+package ttlcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDispatchReentrant(t *testing.T) {
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, DispatchBuffer: 100})
+	defer cache.Close()
+
+	received := make(chan string, 100)
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		cache.Set("evicted "+key, data)
+		received <- key
+	}
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key %d", i)
+		cache.Set(key, "value")
+		cache.Delete(key)
+	}
+
+	for i := 0; i < 10; i++ {
+		select {
+		case key := <-received:
+			if expected := fmt.Sprintf("key %d", i); key != expected {
+				t.Errorf("Expected `%s` to be delivered in order, got `%s`", expected, key)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the callback to be dispatched without deadlocking")
+		}
+	}
+	if _, exists := cache.Get("evicted key 0"); !exists {
+		t.Errorf("Expected the callback to have stored `evicted key 0`")
+	}
+}
+
+func TestDispatchDropped(t *testing.T) {
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, DispatchBuffer: 1})
+	defer cache.Close()
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	cache.OnAccess = func(key string) {
+		started <- struct{}{}
+		<-release
+	}
+	cache.Set("hello", "world")
+	cache.Get("hello")
+	<-started
+
+	begin := time.Now()
+	cache.Get("hello")
+	cache.Get("hello")
+	cache.Get("hello")
+	if elapsed := time.Since(begin); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected a blocked callback to not hold up lookups, took %s", elapsed)
+	}
+	if dropped := cache.DroppedCallbacks(); dropped != 2 {
+		t.Errorf("Expected 2 callbacks to be dropped once the queue was full, got %d", dropped)
+	}
+	close(release)
+}
+
+func TestDispatchPanic(t *testing.T) {
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, DispatchBuffer: 10})
+	defer cache.Close()
+	panics := make(chan interface{}, 10)
+	cache.OnPanic = func(r interface{}) {
+		panics <- r
+	}
+	delivered := make(chan string, 10)
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		if key == "panic" {
+			panic(key)
+		}
+		delivered <- key
+	}
+
+	cache.Set("panic", "value")
+	cache.Delete("panic")
+	cache.Set("next", "value")
+	cache.Delete("next")
+	select {
+	case key := <-delivered:
+		if key != "next" {
+			t.Errorf("Expected `next` to be delivered, got `%s`", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the dispatcher to survive the panic")
+	}
+	if r := <-panics; r != "panic" {
+		t.Errorf("Expected the panic to be handed to OnPanic, got %v", r)
+	}
+}
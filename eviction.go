@@ -0,0 +1,164 @@
+package ttlcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// EvictionReason describes why an item left the cache
+type EvictionReason int
+
+const (
+	// Expired items outlived their ttl
+	Expired EvictionReason = iota
+	// Deleted items were removed explicitly
+	Deleted
+	// Replaced items were overwritten by a new value for the same key
+	Replaced
+	// Evicted items were removed to keep the cache within its limits
+	Evicted
+	// Flushed items were removed by clearing the whole cache
+	Flushed
+	// Shutdown items were still in the cache when it was closed with CloseAndDrain
+	Shutdown
+)
+
+func (reason EvictionReason) String() string {
+	switch reason {
+	case Expired:
+		return "expired"
+	case Deleted:
+		return "deleted"
+	case Replaced:
+		return "replaced"
+	case Evicted:
+		return "evicted"
+	case Flushed:
+		return "flushed"
+	case Shutdown:
+		return "shutdown"
+	}
+	return "unknown"
+}
+
+// EvictionEvent describes an item that left the cache
+type EvictionEvent[K comparable, V any] struct {
+	Key    K
+	Data   V
+	Reason EvictionReason
+	At     time.Time
+}
+
+// evict records the removal of item, reporting Expired instead
+// of the given reason if the item had already outlived its ttl
+func evict[K comparable, V any](evictions []EvictionEvent[K, V], key K, item *Item[V], reason EvictionReason, now time.Time) []EvictionEvent[K, V] {
+	if item.expired(now) {
+		reason = Expired
+	}
+	return append(evictions, EvictionEvent[K, V]{Key: key, Data: item.data, Reason: reason, At: now})
+}
+
+// OverflowPolicy decides what happens to a notification sent on FinishedItems
+// or Evictions while the channel buffer is full
+type OverflowPolicy int
+
+const (
+	// DropNewest drops the notification, keeping the older ones for the consumer
+	DropNewest OverflowPolicy = iota
+	// DropOldest discards the oldest buffered notification to make room
+	DropOldest
+	// Block waits for the consumer to make room. Notifications are sent once
+	// the cache mutex is released, so a slow consumer does not freeze the cache,
+	// but it holds up the operation that removed the items, or the cleanup
+	// goroutine for expired items, until the cache is closed
+	Block
+	// Disabled allocates no notification channels, so nothing is ever sent
+	Disabled
+)
+
+// notify counts evictions, invokes the eviction callbacks and publishes the
+// events on Evictions, it must not be called with the cache mutex held
+func (cache *Cache[K, V]) notify(callback func(key K, data V, reason EvictionReason), evictions []EvictionEvent[K, V]) {
+	onExpire := cache.OnExpire
+	for _, e := range evictions {
+		cache.stats.evicted(e.Reason)
+		e := e
+		if callback != nil {
+			cache.dispatch(func() { callback(e.Key, e.Data, e.Reason) })
+		}
+		if onExpire != nil && e.Reason == Expired {
+			cache.dispatch(func() { onExpire(e.Key, e.Data) })
+		}
+	}
+	if len(evictions) == 0 || cache.Evictions == nil {
+		return
+	}
+	cache.mutex.RLock()
+	keysOnly := cache.keysOnly
+	cache.mutex.RUnlock()
+	cache.channelsMutex.RLock()
+	if !cache.channelsClosed {
+		for _, e := range evictions {
+			if keysOnly {
+				e.Data = *new(V)
+			}
+			atomic.AddInt64(&cache.droppedEvents, publish(cache.Evictions, e, cache.overflow, cache.done))
+		}
+	}
+	cache.channelsMutex.RUnlock()
+}
+
+// finish publishes the data of swept items on FinishedItems, giving up on
+// blocking sends once stop is closed, it must not be called with the cache mutex held
+func (cache *Cache[K, V]) finish(evictions []EvictionEvent[K, V], stop <-chan struct{}) {
+	if len(evictions) == 0 || cache.FinishedItems == nil {
+		return
+	}
+	cache.mutex.RLock()
+	keysOnly := cache.keysOnly
+	cache.mutex.RUnlock()
+	if keysOnly {
+		return
+	}
+	cache.channelsMutex.RLock()
+	if !cache.channelsClosed {
+		for _, e := range evictions {
+			atomic.AddInt64(&cache.dropped, publish(cache.FinishedItems, e.Data, cache.overflow, stop))
+		}
+	}
+	cache.channelsMutex.RUnlock()
+}
+
+// publish sends v on ch following overflow, blocking sends give up once stop
+// is closed. It returns the number of notifications dropped on the way
+func publish[T any](ch chan T, v T, overflow OverflowPolicy, stop <-chan struct{}) (dropped int64) {
+	switch overflow {
+	case Block:
+		select {
+		case ch <- v:
+			return 0
+		case <-stop:
+			return 1
+		}
+	case DropOldest:
+		// unbuffered channels have nothing to discard
+		for cap(ch) > 0 {
+			select {
+			case ch <- v:
+				return dropped
+			default:
+			}
+			select {
+			case <-ch:
+				dropped++
+			default:
+			}
+		}
+	}
+	select {
+	case ch <- v:
+		return dropped
+	default:
+		return dropped + 1
+	}
+}
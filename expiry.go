@@ -0,0 +1,184 @@
+package ttlcache
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+)
+
+// expiryEntry is the position of an expiring item in the expiry queue
+// at is never later than the deadline of the item: touching an item only
+// moves its deadline forward, and the entry catches up once it is popped
+type expiryEntry[K comparable, V any] struct {
+	key  K
+	item *Item[V]
+	at   time.Time
+}
+
+// expiryQueue is a min-heap of the expiring items by deadline, so that a sweep
+// only visits the items that may have expired instead of the whole map
+type expiryQueue[K comparable, V any] []*expiryEntry[K, V]
+
+func (queue expiryQueue[K, V]) Len() int { return len(queue) }
+
+func (queue expiryQueue[K, V]) Less(i, j int) bool { return queue[i].at.Before(queue[j].at) }
+
+func (queue expiryQueue[K, V]) Swap(i, j int) {
+	queue[i], queue[j] = queue[j], queue[i]
+	queue[i].item.index = i
+	queue[j].item.index = j
+}
+
+func (queue *expiryQueue[K, V]) Push(x any) {
+	entry := x.(*expiryEntry[K, V])
+	entry.item.index = len(*queue)
+	*queue = append(*queue, entry)
+}
+
+func (queue *expiryQueue[K, V]) Pop() any {
+	old := *queue
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	entry.item.index = -1
+	*queue = old[:len(old)-1]
+	return entry
+}
+
+// requeue moves the front of the expiry queue, whose item was touched since it
+// was queued, back to its deadline, or drops it if the item no longer expires,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) requeue() {
+	entry := cache.queue[0]
+	if entry.at = entry.item.deadline(); entry.at.IsZero() {
+		heap.Pop(&cache.queue)
+	} else {
+		heap.Fix(&cache.queue, 0)
+	}
+}
+
+// schedule adds a stored item to the expiry queue unless it never expires,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) schedule(key K, item *Item[V]) {
+	item.index = -1
+	if deadline := item.deadline(); !deadline.IsZero() {
+		heap.Push(&cache.queue, &expiryEntry[K, V]{key: key, item: item, at: deadline})
+	}
+}
+
+// unschedule removes an item from the expiry queue,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) unschedule(item *Item[V]) {
+	if item.index >= 0 && item.index < len(cache.queue) && cache.queue[item.index].item == item {
+		heap.Remove(&cache.queue, item.index)
+	}
+}
+
+// DrainExpired removes the expired items from the cache in a single locked pass
+// and returns them with the deadline they expired at, in deadline order, for
+// callers that prefer to process them on their own goroutine. It is a sweep
+// like Cleanup, reporting the items as Expired to OnEvicted, OnExpire and
+// Evictions, but the reaped items are returned instead of sent on FinishedItems
+func (cache *Cache[K, V]) DrainExpired() []Entry[K, V] {
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return nil
+	}
+	now := cache.now()
+	var entries []Entry[K, V]
+	var evictions []EvictionEvent[K, V]
+	for len(cache.queue) > 0 && cache.queue[0].at.Before(now) {
+		entry := cache.queue[0]
+		if entry.item.expired(now) {
+			entries = append(entries, cache.entry(entry.key, entry.item))
+			evictions = cache.remove(evictions, entry.key, entry.item, Expired)
+			continue
+		}
+		cache.requeue()
+	}
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	// touched items may be reaped after items with a later deadline
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ExpiresAt.Before(entries[j].ExpiresAt) })
+	return entries
+}
+
+// SoonestExpiry returns the nearest deadline among the live items, so that an
+// external scheduler can wake up when it passes instead of polling. It returns
+// false if no live item expires. The queued deadlines are lower bounds of the
+// actual ones, which touches extend, so only the front of the expiry queue that
+// may hold an earlier deadline than the best one found is visited
+func (cache *Cache[K, V]) SoonestExpiry() (time.Time, bool) {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	now := cache.now()
+	var soonest time.Time
+	pending := []int{0}
+	for len(pending) > 0 {
+		i := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if i >= len(cache.queue) {
+			continue
+		}
+		entry := cache.queue[i]
+		if !soonest.IsZero() && !entry.at.Before(soonest) {
+			// the entries below are queued no earlier
+			continue
+		}
+		if deadline := entry.item.deadline(); !entry.item.expired(now) && (soonest.IsZero() || deadline.Before(soonest)) {
+			soonest = deadline
+		}
+		pending = append(pending, 2*i+1, 2*i+2)
+	}
+	return soonest, !soonest.IsZero()
+}
+
+// expireBefore brings the queued deadlines no later than bound, for settings
+// that may shorten the deadline of items the next time they are touched,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) expireBefore(bound time.Time) {
+	for _, entry := range cache.queue {
+		if entry.at.After(bound) {
+			entry.at = bound
+		}
+	}
+	heap.Init(&cache.queue)
+}
+
+// capLifetimes brings the queued deadlines no later than the max lifetime of
+// their item, it must be called with the cache mutex held
+func (cache *Cache[K, V]) capLifetimes(max time.Duration) {
+	for _, entry := range cache.queue {
+		if limit := entry.item.created.Add(max); entry.at.After(limit) {
+			entry.at = limit
+		}
+	}
+	heap.Init(&cache.queue)
+}
+
+// sweep removes the expired items at the front of the expiry queue, moving
+// the items that were touched since they were queued back to their deadline,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) sweep(evictions []EvictionEvent[K, V], now time.Time) []EvictionEvent[K, V] {
+	evictions, _ = cache.sweepUpTo(evictions, now, 0)
+	return evictions
+}
+
+// sweepUpTo is sweep handling at most limit queued items, or all of them if limit
+// is not positive, and reports whether items are left for another batch,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) sweepUpTo(evictions []EvictionEvent[K, V], now time.Time, limit int) ([]EvictionEvent[K, V], bool) {
+	for handled := 0; len(cache.queue) > 0 && cache.queue[0].at.Before(now); handled++ {
+		if limit > 0 && handled == limit {
+			return evictions, true
+		}
+		entry := cache.queue[0]
+		if entry.item.expired(now) {
+			evictions = cache.remove(evictions, entry.key, entry.item, Expired)
+			continue
+		}
+		cache.requeue()
+	}
+	return evictions, false
+}
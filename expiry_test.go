@@ -0,0 +1,299 @@
+package ttlcache
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// checkQueue verifies that the expiry queue is a min-heap holding exactly the expiring items
+func checkQueue(t *testing.T, cache *Cache[string, string]) {
+	t.Helper()
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	expiring := 0
+	for _, item := range cache.items {
+		if !item.deadline().IsZero() {
+			expiring++
+		}
+	}
+	if len(cache.queue) != expiring {
+		t.Errorf("Expected the queue to hold the %d expiring items, got %d", expiring, len(cache.queue))
+	}
+	for i, entry := range cache.queue {
+		if entry.item.index != i {
+			t.Errorf("Expected the entry of `%s` to know its position %d, got %d", entry.key, i, entry.item.index)
+		}
+		if cache.items[entry.key] != entry.item {
+			t.Errorf("Expected the entry of `%s` to refer to the stored item", entry.key)
+		}
+		if entry.at.After(entry.item.deadline()) {
+			t.Errorf("Expected the entry of `%s` to not be later than its deadline", entry.key)
+		}
+		if parent := (i - 1) / 2; i > 0 && cache.queue[parent].at.After(entry.at) {
+			t.Errorf("Expected the queue to be ordered by deadline at %d", i)
+		}
+	}
+}
+
+func TestExpiryQueue(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Minute, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.SetMaxItems(150)
+
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("key %d", random.Intn(200))
+		switch random.Intn(6) {
+		case 0:
+			cache.Set(key, "value")
+		case 1:
+			cache.SetWithTTL(key, "value", time.Duration(random.Intn(120))*time.Second)
+		case 2:
+			cache.SetWithTTL(key, "value", -1)
+		case 3:
+			cache.Get(key)
+		case 4:
+			cache.Delete(key)
+		case 5:
+			clock.Advance(time.Duration(random.Intn(10)) * time.Second)
+			cache.Cleanup()
+		}
+	}
+	checkQueue(t, cache)
+
+	cache.SetTTL(time.Second)
+	checkQueue(t, cache)
+	cache.SetMaxTTL(time.Second)
+	checkQueue(t, cache)
+	clock.Advance(2 * time.Second)
+	cache.Cleanup()
+	checkQueue(t, cache)
+	if count := cache.CountLive(); count != cache.Count() {
+		t.Errorf("Expected every expired item to be swept, %d of %d live", count, cache.Count())
+	}
+	cache.Flush()
+	checkQueue(t, cache)
+}
+
+func TestSweepTouchedItem(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Minute, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	cache.Set("touched", "value")
+	cache.Set("untouched", "value")
+	clock.Advance(50 * time.Second)
+	cache.Get("touched")
+	clock.Advance(20 * time.Second)
+
+	if removed := cache.Cleanup(); removed != 1 {
+		t.Errorf("Expected only the untouched item to be swept, %d removed", removed)
+	}
+	if _, exists := cache.Peek("touched"); !exists {
+		t.Errorf("Expected the touched item to be kept")
+	}
+	checkQueue(t, cache)
+	clock.Advance(time.Minute)
+	if removed := cache.Cleanup(); removed != 1 {
+		t.Errorf("Expected the touched item to be swept once expired, %d removed", removed)
+	}
+}
+
+func TestCleanupBatches(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Minute, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.SetCleanupBatchSize(2)
+
+	for i := 0; i < 10; i++ {
+		cache.Set(fmt.Sprintf("key %d", i), "old")
+	}
+	clock.Advance(2 * time.Minute)
+	refreshed := false
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		if refreshed {
+			return
+		}
+		// runs between the first and the second batch
+		refreshed = true
+		for i := 0; i < 10; i++ {
+			cache.Set(fmt.Sprintf("key %d", i), "new")
+		}
+	}
+
+	if removed := cache.Cleanup(); removed != 2 {
+		t.Errorf("Expected only the first batch to be swept, %d removed", removed)
+	}
+	if count := cache.Count(); count != 10 {
+		t.Errorf("Expected the items set between batches to be kept, got %d", count)
+	}
+	checkQueue(t, cache)
+}
+
+func TestCleanupBatchesServeReaders(t *testing.T) {
+	cache := NewCache(time.Millisecond)
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.SetCleanupBatchSize(100)
+
+	const expired = 20000
+	for i := 0; i < expired; i++ {
+		cache.Set(fmt.Sprintf("key %d", i), "value")
+	}
+	cache.SetWithTTL("live", "value", -1)
+	time.Sleep(5 * time.Millisecond)
+
+	var reads int64
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			cache.Get("live")
+			atomic.AddInt64(&reads, 1)
+		}
+	}()
+
+	first := true
+	var pending int
+	served := false
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		if !first {
+			return
+		}
+		first = false
+		pending = cache.Count() - 1
+		before := atomic.LoadInt64(&reads)
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) && !served {
+			served = atomic.LoadInt64(&reads) > before
+			runtime.Gosched()
+		}
+	}
+
+	if removed := cache.Cleanup(); removed != expired {
+		t.Errorf("Expected all %d expired items to be swept, %d removed", expired, removed)
+	}
+	if pending == 0 {
+		t.Errorf("Expected expired items left after the first batch")
+	}
+	if !served {
+		t.Errorf("Expected the reader to be served while the sweep was in progress")
+	}
+}
+
+func BenchmarkCleanup(b *testing.B) {
+	for _, size := range []int{1000, 1000000} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			cache := NewCache(time.Hour)
+			defer cache.Close()
+			cache.SetCleanupInterval(time.Hour)
+			for i := 0; i < size; i++ {
+				cache.Set(fmt.Sprintf("key %d", i), "value")
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				for j := 0; j < 10; j++ {
+					cache.SetWithTTL(fmt.Sprintf("short %d", j), "value", time.Nanosecond)
+				}
+				b.StartTimer()
+				cache.Cleanup()
+			}
+		})
+	}
+}
+
+func TestSoonestExpiry(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Hour, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	if _, ok := cache.SoonestExpiry(); ok {
+		t.Errorf("Expected an empty cache to have no expiry")
+	}
+	cache.SetWithTTL("forever", "value", -1)
+	if _, ok := cache.SoonestExpiry(); ok {
+		t.Errorf("Expected items that never expire to have no expiry")
+	}
+
+	now := clock.Now()
+	for i := 1; i <= 50; i++ {
+		cache.SetWithTTL(fmt.Sprintf("key %d", i), "value", time.Duration(i)*time.Minute)
+	}
+	if soonest, _ := cache.SoonestExpiry(); !soonest.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected the earliest deadline %s, got %s", now.Add(time.Minute), soonest)
+	}
+
+	// touching the earliest items moves their deadline past the queued one
+	clock.Advance(30 * time.Second)
+	cache.Get("key 1")
+	cache.Get("key 2")
+	if soonest, _ := cache.SoonestExpiry(); !soonest.Equal(now.Add(90 * time.Second)) {
+		t.Errorf("Expected the touched deadline %s, got %s", now.Add(90*time.Second), soonest)
+	}
+
+	// expired items waiting for a sweep are not live
+	clock.Advance(3*time.Minute + 15*time.Second)
+	if soonest, _ := cache.SoonestExpiry(); !soonest.Equal(now.Add(4 * time.Minute)) {
+		t.Errorf("Expected the earliest live deadline %s, got %s", now.Add(4*time.Minute), soonest)
+	}
+}
+
+func TestDrainExpired(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Minute, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	var expired []string
+	cache.OnExpire = func(key string, data string) {
+		expired = append(expired, key)
+	}
+
+	start := clock.Now()
+	cache.SetWithTTL("first", "1", time.Second)
+	cache.SetWithTTL("second", "2", 2*time.Second)
+	cache.SetWithTTL("touched", "3", time.Second)
+	cache.Set("live", "4")
+	clock.Advance(500 * time.Millisecond)
+	cache.Get("touched")
+	clock.Advance(2 * time.Second)
+
+	entries := cache.DrainExpired()
+	expected := []Entry[string, string]{
+		{Key: "first", Data: "1", ExpiresAt: start.Add(time.Second)},
+		{Key: "touched", Data: "3", ExpiresAt: start.Add(1500 * time.Millisecond)},
+		{Key: "second", Data: "2", ExpiresAt: start.Add(2 * time.Second)},
+	}
+	if fmt.Sprint(entries) != fmt.Sprint(expected) {
+		t.Errorf("Expected the drained entries %v, got %v", expected, entries)
+	}
+	if count := cache.Count(); count != 1 || !cache.Has("live") {
+		t.Errorf("Expected only the live item to be left, got %v", cache.Keys())
+	}
+	if len(expired) != 3 {
+		t.Errorf("Expected the drained items to be reported as expired, got %v", expired)
+	}
+	select {
+	case data := <-cache.FinishedItems:
+		t.Errorf("Expected nothing to be sent on FinishedItems, got %s", data)
+	default:
+	}
+	if entries := cache.DrainExpired(); len(entries) != 0 {
+		t.Errorf("Expected nothing left to drain, got %v", entries)
+	}
+	checkQueue(t, cache)
+}
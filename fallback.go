@@ -0,0 +1,33 @@
+package ttlcache
+
+// Getter looks up values, as a Cache does. It is implemented by *Cache,
+// so that caches can be chained with SetFallback
+type Getter[K comparable, V any] interface {
+	Get(key K) (V, bool)
+}
+
+// SetFallback chains a secondary tier to the cache, typically a larger or shared
+// cache behind an in-process one. Misses of Get and GetContext consult next
+// before the loader, and a value found there is stored in the cache with a fresh
+// ttl, so that the following lookups hit the cache
+// A nil fallback disables it, which is the default
+func (cache *Cache[K, V]) SetFallback(next Getter[K, V]) {
+	cache.mutex.Lock()
+	cache.fallback = next
+	cache.mutex.Unlock()
+}
+
+// fallBack looks up a missing key in the fallback tier, promoting a hit into the
+// cache. A closed cache does not consult the fallback
+func (cache *Cache[K, V]) fallBack(key K) (data V, found bool) {
+	cache.mutex.RLock()
+	next, closed := cache.fallback, cache.closed
+	cache.mutex.RUnlock()
+	if closed || next == nil {
+		return
+	}
+	if data, found = next.Get(key); found {
+		cache.Set(key, data)
+	}
+	return
+}
@@ -0,0 +1,84 @@
+package ttlcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// countingGetter counts the lookups reaching a fallback tier
+type countingGetter struct {
+	Getter[string, string]
+	lookups int
+}
+
+func (getter *countingGetter) Get(key string) (string, bool) {
+	getter.lookups++
+	return getter.Getter.Get(key)
+}
+
+func TestFallback(t *testing.T) {
+	shared := NewCache(time.Hour)
+	defer shared.Close()
+	shared.Set("key", "value")
+	next := &countingGetter{Getter: shared}
+
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	cache.SetFallback(next)
+
+	if data, found := cache.Get("key"); !found || data != "value" {
+		t.Errorf("Expected a miss to be served by the fallback, got %q, %v", data, found)
+	}
+	if data, exists := cache.Peek("key"); !exists || data != "value" {
+		t.Errorf("Expected the fallback hit to be promoted, got %q, %v", data, exists)
+	}
+	if ttl, _ := cache.TTLRemaining("key"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected the promoted item to get the ttl of the cache, got %s", ttl)
+	}
+	if stats := cache.Stats(); stats.Misses != 1 {
+		t.Errorf("Expected the first lookup to count as a miss of the cache, got %d", stats.Misses)
+	}
+
+	cache.Get("key")
+	if data, found, err := cache.GetContext(context.Background(), "key"); !found || err != nil || data != "value" {
+		t.Errorf("Expected GetContext to hit the cache, got %q, %v, %v", data, found, err)
+	}
+	if next.lookups != 1 {
+		t.Errorf("Expected the following lookups to hit the cache, the fallback saw %d", next.lookups)
+	}
+
+	if _, found := cache.Get("missing"); found {
+		t.Errorf("Expected a key missing from both tiers to miss")
+	}
+	if cache.Has("missing") {
+		t.Errorf("Expected nothing to be promoted for a miss of both tiers")
+	}
+
+	cache.SetFallback(nil)
+	cache.Delete("key")
+	if _, found := cache.Get("key"); found {
+		t.Errorf("Expected no fallback once it is removed")
+	}
+}
+
+func TestFallbackClosed(t *testing.T) {
+	shared := NewCache(time.Hour)
+	defer shared.Close()
+	shared.Set("key", "value")
+	next := &countingGetter{Getter: shared}
+
+	cache := NewCache(time.Minute)
+	cache.SetFallback(next)
+	cache.Close()
+
+	if _, found := cache.Get("key"); found {
+		t.Errorf("Expected a closed cache to miss")
+	}
+	if _, _, err := cache.GetContext(context.Background(), "key"); err != ErrClosed {
+		t.Errorf("Expected GetContext to return ErrClosed, got %v", err)
+	}
+	if next.lookups != 0 {
+		t.Errorf("Expected a closed cache to not consult the fallback, got %d lookups", next.lookups)
+	}
+}
@@ -0,0 +1,313 @@
+package ttlcache
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// fileEntry is the persisted form of an item. gob leaves the fields missing from
+// the files of earlier versions zero, which restores the items without them
+type fileEntry[K comparable, V any] struct {
+	Key       K
+	Data      V
+	TTL       time.Duration
+	ExpiresAt time.Time
+	Written   time.Time
+	Fixed     time.Time
+	Created   time.Time
+	Weight    int64
+	Tags      []string
+}
+
+// SaveFile writes the live items of the cache to path, along with their deadlines,
+// creation times, weights and tags
+func (cache *Cache[K, V]) SaveFile(path string) error {
+	cache.mutex.RLock()
+	if cache.closed {
+		cache.mutex.RUnlock()
+		return ErrClosed
+	}
+	entries := make([]fileEntry[K, V], 0, len(cache.items))
+	for key, item := range cache.items {
+		if !cache.expired(item) {
+			entries = append(entries, fileEntry[K, V]{
+				Key:       key,
+				Data:      item.data,
+				TTL:       item.ttl,
+				ExpiresAt: item.deadline(),
+				Written:   item.written,
+				Fixed:     item.fixed,
+				Created:   item.created,
+				Weight:    item.weight,
+				Tags:      item.tags,
+			})
+		}
+	}
+	cache.mutex.RUnlock()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(file).Encode(entries); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadFile restores the items saved to path with SaveFile, keeping their saved
+// deadlines and dropping the ones that have expired since
+func (cache *Cache[K, V]) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var entries []fileEntry[K, V]
+	if err := gob.NewDecoder(file).Decode(&entries); err != nil {
+		return err
+	}
+
+	now := cache.now()
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return ErrClosed
+	}
+	var evictions []EvictionEvent[K, V]
+	for _, entry := range entries {
+		item := &Item[V]{
+			data:    entry.Data,
+			ttl:     entry.TTL,
+			weight:  entry.Weight,
+			written: entry.Written,
+			fixed:   entry.Fixed,
+			created: entry.Created,
+			tags:    entry.Tags,
+		}
+		evictions = cache.restore(evictions, entry.Key, item, entry.ExpiresAt, now)
+	}
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return nil
+}
+
+// Entry is an exported item, as returned by Export, or a reaped one, as returned by DrainExpired
+// Items that never expire have a zero ExpiresAt. Deadline is the part of ExpiresAt
+// that lookups cannot extend, as set by SetWithDeadline, SetExpireAfterWrite or
+// SetStrictExpiry, and is zero for items that only expire when left idle
+type Entry[K comparable, V any] struct {
+	Key       K
+	Data      V
+	ExpiresAt time.Time
+	Deadline  time.Time
+}
+
+// entry describes an item as an Entry, with a copy of its data under SetCopyOnGet
+func (cache *Cache[K, V]) entry(key K, item *Item[V]) Entry[K, V] {
+	return Entry[K, V]{Key: key, Data: cache.loaned(item.data), ExpiresAt: item.deadline(), Deadline: item.written}
+}
+
+// Export returns the live items of the cache along with their deadlines, in no
+// particular order and without extending their life, to be passed to Import
+func (cache *Cache[K, V]) Export() []Entry[K, V] {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	if cache.closed {
+		return nil
+	}
+	entries := make([]Entry[K, V], 0, len(cache.items))
+	for key, item := range cache.items {
+		if !cache.expired(item) {
+			entries = append(entries, cache.entry(key, item))
+		}
+	}
+	return entries
+}
+
+// streamChunk is the number of items Stream copies per acquisition of the read lock
+const streamChunk = 1000
+
+// Stream emits the live items of the cache on the returned channel, which is
+// closed once every item was sent or ctx is done. Unlike Export, it only holds
+// the keys of the cache and a chunk of entries at a time, and releases the read
+// lock between chunks so writers are not blocked for the whole dump. The result
+// is weakly consistent: items set during the stream may be missed, and items
+// removed or expired before their chunk is copied are skipped
+func (cache *Cache[K, V]) Stream(ctx context.Context) <-chan Entry[K, V] {
+	ch := make(chan Entry[K, V])
+	cache.mutex.RLock()
+	keys := make([]K, 0, len(cache.items))
+	if !cache.closed {
+		for key := range cache.items {
+			keys = append(keys, key)
+		}
+	}
+	cache.mutex.RUnlock()
+
+	go func() {
+		defer close(ch)
+		chunk := make([]Entry[K, V], 0, streamChunk)
+		for start := 0; start < len(keys); start += streamChunk {
+			end := start + streamChunk
+			if end > len(keys) {
+				end = len(keys)
+			}
+			chunk = chunk[:0]
+			cache.mutex.RLock()
+			for _, key := range keys[start:end] {
+				if item, exists := cache.items[key]; exists && !cache.expired(item) {
+					chunk = append(chunk, cache.entry(key, item))
+				}
+			}
+			cache.mutex.RUnlock()
+			for _, entry := range chunk {
+				if ctx.Err() != nil {
+					return
+				}
+				select {
+				case ch <- entry:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+// Import stores entries returned by Export, keeping their deadlines and dropping
+// the ones that have expired since, like LoadFile does for a saved file
+// Once imported, sliding expiration extends the items by the default ttl, up to
+// their Deadline. Items whose ExpiresAt is their Deadline are not extended at all
+func (cache *Cache[K, V]) Import(entries []Entry[K, V]) {
+	now := cache.now()
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return
+	}
+	var evictions []EvictionEvent[K, V]
+	for _, entry := range entries {
+		item := &Item[V]{data: entry.Data, written: entry.Deadline, fixed: entry.Deadline}
+		if entry.ExpiresAt.IsZero() || entry.ExpiresAt.Equal(entry.Deadline) {
+			item.ttl = -1
+		}
+		evictions = cache.restore(evictions, entry.Key, item, entry.ExpiresAt, now)
+	}
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+}
+
+// restore stores a saved item with its deadline, unless that has passed, as
+// created now unless its creation time was saved,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) restore(evictions []EvictionEvent[K, V], key K, item *Item[V], expiresAt, now time.Time) []EvictionEvent[K, V] {
+	if item.created.IsZero() {
+		item.created = now
+	}
+	if !expiresAt.IsZero() && !expiresAt.After(now) {
+		return evictions
+	}
+	if item.ttl >= 0 {
+		item.expires = &expiresAt
+	}
+	return append(evictions, cache.insert(cache.normalize(key), item)...)
+}
+
+// LoadFrom stores the records read from r, one per line, with the default ttl
+// and returns the number of stored items. A record is either a JSON object
+// with "key" and "value" fields, or a key and a value separated by a tab for
+// caches whose keys and values hold text. Empty lines are skipped
+// Nothing is stored if any line is malformed, the error then names the line
+func (cache *Cache[K, V]) LoadFrom(r io.Reader) (int, error) {
+	type record struct {
+		Key   K `json:"key"`
+		Value V `json:"value"`
+	}
+	var records []record
+	reader := bufio.NewReader(r)
+	for number := 1; ; number++ {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if text := strings.TrimRight(line, "\r\n"); text != "" {
+			var rec record
+			if strings.HasPrefix(text, "{") {
+				if err := json.Unmarshal([]byte(text), &rec); err != nil {
+					return 0, fmt.Errorf("ttlcache: line %d: %v", number, err)
+				}
+			} else {
+				key, value, found := strings.Cut(text, "\t")
+				if !found {
+					return 0, fmt.Errorf("ttlcache: line %d: expected a tab between key and value", number)
+				}
+				if !fromText(key, &rec.Key) || !fromText(value, &rec.Value) {
+					return 0, fmt.Errorf("ttlcache: line %d: %w", number, ErrNotText)
+				}
+			}
+			records = append(records, rec)
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return 0, ErrClosed
+	}
+	var evictions []EvictionEvent[K, V]
+	for _, rec := range records {
+		key := cache.normalize(rec.Key)
+		evictions = append(evictions, cache.set(key, cache.merged(key, rec.Value), 0)...)
+	}
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return len(records), nil
+}
+
+// fromText stores text in dest if it holds strings or byte slices
+func fromText[T any](text string, dest *T) bool {
+	value := reflect.ValueOf(dest).Elem()
+	switch {
+	case value.Kind() == reflect.String:
+		value.SetString(text)
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8:
+		value.SetBytes([]byte(text))
+	default:
+		return false
+	}
+	return true
+}
+
+// LoadFile is a helper to create instance of the Cache struct holding strings,
+// restored from the items saved to path
+func LoadFile(path string, ttl time.Duration) (*Cache[string, string], error) {
+	return Load[string, string](path, ttl)
+}
+
+// Load is a helper to create instance of the Cache struct holding values of type V under keys of type K,
+// restored from the items saved to path
+func Load[K comparable, V any](path string, ttl time.Duration) (*Cache[K, V], error) {
+	cache := New[K, V](ttl)
+	if err := cache.LoadFile(path); err != nil {
+		cache.Close()
+		return nil, err
+	}
+	return cache, nil
+}
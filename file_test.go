@@ -0,0 +1,323 @@
+package ttlcache
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+
+	cache := NewCache(time.Second)
+	cache.SetWithTTL("long", "lived", time.Minute)
+	cache.SetWithTTL("forever", "young", -1)
+	cache.SetWithTTL("short", "lived", 100*time.Millisecond)
+	if err := cache.SaveFile(path); err != nil {
+		t.Fatalf("Expected cache to save, got %v", err)
+	}
+	cache.Close()
+
+	<-time.After(200 * time.Millisecond)
+	loaded, err := LoadFile(path, time.Second)
+	if err != nil {
+		t.Fatalf("Expected cache to load, got %v", err)
+	}
+	defer loaded.Close()
+
+	if count := loaded.Count(); count != 2 {
+		t.Errorf("Expected loaded cache to contain 2 items, got %d", count)
+	}
+	if _, exists := loaded.Get("short"); exists {
+		t.Errorf("Expected expired `short` to be dropped")
+	}
+	remaining, exists := loaded.TTLRemaining("long")
+	if !exists || remaining > time.Minute-200*time.Millisecond || remaining < 50*time.Second {
+		t.Errorf("Expected `long` to keep its saved deadline, got %s remaining", remaining)
+	}
+	if remaining, exists := loaded.TTLRemaining("forever"); !exists || remaining >= 0 {
+		t.Errorf("Expected `forever` to still never expire")
+	}
+
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.gob"), time.Second); err == nil {
+		t.Errorf("Expected loading a missing file to fail")
+	}
+}
+
+func TestLoadFrom(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	input := "hello\tworld\n\n{\"key\": \"json\", \"value\": \"line\"}\r\ntabs\tin\tvalue"
+	loaded, err := cache.LoadFrom(strings.NewReader(input))
+	if err != nil || loaded != 3 {
+		t.Errorf("Expected 3 records to be loaded, got %d (%v)", loaded, err)
+	}
+	for key, expected := range map[string]string{"hello": "world", "json": "line", "tabs": "in\tvalue"} {
+		if data, _ := cache.Get(key); data != expected {
+			t.Errorf("Expected cache to return `%s` for `%s`, got `%s`", expected, key, data)
+		}
+	}
+
+	ints := New[int, int](time.Second)
+	defer ints.Close()
+	if loaded, err := ints.LoadFrom(strings.NewReader("{\"key\": 1, \"value\": 42}\n")); err != nil || loaded != 1 {
+		t.Errorf("Expected JSON lines to load any type, got %d (%v)", loaded, err)
+	}
+	if _, err := ints.LoadFrom(strings.NewReader("1\t42\n")); !errors.Is(err, ErrNotText) {
+		t.Errorf("Expected tab separated lines to need text, got %v", err)
+	}
+}
+
+func TestLoadFromMerge(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	cache.Merge = func(existing, incoming string) string {
+		return existing + "," + incoming
+	}
+
+	cache.Set("log", "first")
+	if _, err := cache.LoadFrom(strings.NewReader("log\tsecond\nnew\tvalue\n")); err != nil {
+		t.Fatalf("Expected records to load, got %v", err)
+	}
+	if data, _ := cache.Peek("log"); data != "first,second" {
+		t.Errorf("Expected a loaded record to be merged into the live item, got %q", data)
+	}
+	if data, _ := cache.Peek("new"); data != "value" {
+		t.Errorf("Expected a loaded record for a missing key to be stored, got %q", data)
+	}
+}
+
+func TestLoadFromMalformed(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	_, err := cache.LoadFrom(strings.NewReader("a\t1\nb\t2\nno tab here\n"))
+	if err == nil || err.Error() != "ttlcache: line 3: expected a tab between key and value" {
+		t.Errorf("Expected an error naming line 3, got %v", err)
+	}
+	_, err = cache.LoadFrom(strings.NewReader("a\t1\n{\"key\": \n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "ttlcache: line 2: ") {
+		t.Errorf("Expected an error naming line 2, got %v", err)
+	}
+	if count := cache.Count(); count != 0 {
+		t.Errorf("Expected a malformed stream to load nothing, got %d items", count)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	source := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer source.Close()
+	source.SetWithTTL("long", "lived", time.Minute)
+	source.SetWithTTL("short", "lived", 100*time.Millisecond)
+	source.SetWithTTL("forever", "lived", -1)
+	entries := source.Export()
+	if len(entries) != 3 {
+		t.Errorf("Expected 3 exported entries, got %d", len(entries))
+	}
+
+	clock.Advance(200 * time.Millisecond)
+	target := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer target.Close()
+	target.Import(entries)
+	if target.Has("short") {
+		t.Errorf("Expected entries that expired since the export to be dropped")
+	}
+	if remaining, found := target.TTLRemaining("long"); !found || remaining != time.Minute-200*time.Millisecond {
+		t.Errorf("Expected `long` to keep its deadline, %s remaining", remaining)
+	}
+	if remaining, found := target.TTLRemaining("forever"); !found || remaining >= 0 {
+		t.Errorf("Expected `forever` to never expire")
+	}
+	if data, found := target.Get("long"); !found || data != "lived" {
+		t.Errorf("Expected `long` to carry over its data")
+	}
+}
+
+func TestExportImportDeadline(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	source := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer source.Close()
+	deadline := clock.Now().Add(time.Minute)
+	source.SetWithDeadline("fixed", "lived", deadline)
+
+	target := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer target.Close()
+	target.SetCleanupInterval(time.Hour)
+	target.Import(source.Export())
+	clock.Advance(30 * time.Second)
+	target.Get("fixed")
+	if expiresAt, found := target.ExpiresAt("fixed"); !found || !expiresAt.Equal(deadline) {
+		t.Errorf("Expected `fixed` to keep its deadline without sliding, got %s", expiresAt)
+	}
+	target.Replace("fixed", "updated")
+	if expiresAt, _ := target.ExpiresAt("fixed"); !expiresAt.Equal(deadline) {
+		t.Errorf("Expected updates of `fixed` to keep its deadline, got %s", expiresAt)
+	}
+}
+
+func TestSaveLoadFileDeadline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	deadline := clock.Now().Add(time.Minute)
+	cache.SetWithDeadline("fixed", "lived", deadline)
+	if err := cache.SaveFile(path); err != nil {
+		t.Fatalf("Expected cache to save, got %v", err)
+	}
+	cache.Close()
+
+	loaded := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer loaded.Close()
+	loaded.SetCleanupInterval(time.Hour)
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("Expected cache to load, got %v", err)
+	}
+	clock.Advance(30 * time.Second)
+	if _, found := loaded.Get("fixed"); !found {
+		t.Fatalf("Expected `fixed` to be loaded")
+	}
+	if expiresAt, _ := loaded.ExpiresAt("fixed"); !expiresAt.Equal(deadline) {
+		t.Errorf("Expected `fixed` to keep its deadline, got %s", expiresAt)
+	}
+	loaded.Replace("fixed", "updated")
+	clock.Advance(31 * time.Second)
+	if _, found := loaded.Get("fixed"); found {
+		t.Errorf("Expected `fixed` to expire at its saved deadline")
+	}
+}
+
+func TestStream(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	for i := 0; i < 5000; i++ {
+		cache.Set(fmt.Sprintf("key %d", i), "value")
+	}
+
+	seen := map[string]bool{}
+	for entry := range cache.Stream(context.Background()) {
+		if entry.Data != "value" || entry.ExpiresAt.IsZero() || seen[entry.Key] {
+			t.Errorf("Expected every item once with its deadline, got %+v", entry)
+		}
+		seen[entry.Key] = true
+	}
+	if len(seen) != 5000 {
+		t.Errorf("Expected 5000 streamed entries, got %d", len(seen))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := cache.Stream(ctx)
+	for i := 0; i < 100; i++ {
+		<-stream
+		// writers are not blocked while the stream is consumed
+		cache.Set(fmt.Sprintf("new %d", i), "value")
+	}
+	cancel()
+	received := 0
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, open := <-stream:
+			if !open {
+				if received > 1 {
+					t.Errorf("Expected the stream to stop promptly once cancelled, got %d more entries", received)
+				}
+				return
+			}
+			received++
+		case <-timeout:
+			t.Fatalf("Expected the stream to be closed once cancelled")
+		}
+	}
+}
+
+func TestSaveLoadFileWriteDeadline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Hour, Clock: clock})
+	cache.SetExpireAfterWrite(30 * time.Second)
+	deadline := clock.Now().Add(30 * time.Second)
+	cache.SetWithTTL("forever", "value", -1)
+	if err := cache.SaveFile(path); err != nil {
+		t.Fatalf("Expected cache to save, got %v", err)
+	}
+	cache.Close()
+
+	loaded := NewWithConfig[string, string](Config{TTL: time.Hour, Clock: clock})
+	defer loaded.Close()
+	loaded.SetCleanupInterval(time.Hour)
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("Expected cache to load, got %v", err)
+	}
+	if expiresAt, found := loaded.ExpiresAt("forever"); !found || !expiresAt.Equal(deadline) {
+		t.Errorf("Expected `forever` to keep its write deadline, got %s", expiresAt)
+	}
+	clock.Advance(31 * time.Second)
+	if _, found := loaded.Get("forever"); found {
+		t.Errorf("Expected `forever` to expire after the write ttl once loaded")
+	}
+}
+
+func TestSaveLoadFileMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Hour, Clock: clock})
+	created := clock.Now()
+	cache.SetWithTags("tagged", "value", "group")
+	cache.SetWithWeight("weighted", "value", 100)
+	if err := cache.SaveFile(path); err != nil {
+		t.Fatalf("Expected cache to save, got %v", err)
+	}
+	cache.Close()
+
+	clock.Advance(time.Minute)
+	loaded := NewWithConfig[string, string](Config{TTL: time.Hour, Clock: clock})
+	defer loaded.Close()
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("Expected cache to load, got %v", err)
+	}
+	if info, _ := loaded.GetEntry("tagged"); !info.CreatedAt.Equal(created) {
+		t.Errorf("Expected `tagged` to keep its creation time, got %s", info.CreatedAt)
+	}
+	if size := loaded.SizeBytes(); size != 105 {
+		t.Errorf("Expected `weighted` to keep its weight, got %d bytes", size)
+	}
+	if invalidated := loaded.InvalidateTag("group"); invalidated != 1 || loaded.Has("tagged") {
+		t.Errorf("Expected `tagged` to keep its tags, %d invalidated", invalidated)
+	}
+}
+
+func TestLoadFileEarlierFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+	type earlierEntry struct {
+		Key       string
+		Data      string
+		TTL       time.Duration
+		ExpiresAt time.Time
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Expected the file to be created, got %v", err)
+	}
+	entries := []earlierEntry{{Key: "hello", Data: "world", TTL: -1}}
+	if err := gob.NewEncoder(file).Encode(entries); err != nil {
+		t.Fatalf("Expected the entries to be encoded, got %v", err)
+	}
+	file.Close()
+
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	if err := cache.LoadFile(path); err != nil {
+		t.Fatalf("Expected a file of an earlier version to load, got %v", err)
+	}
+	if data, found := cache.Get("hello"); !found || data != "world" {
+		t.Errorf("Expected `hello` to be restored, got %q, %v", data, found)
+	}
+}
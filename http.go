@@ -0,0 +1,187 @@
+package ttlcache
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// ErrKeyNotParsable is returned when a key is looked up from its text, as by
+// the requests of Handler, but the keys of the cache cannot be parsed from text
+var ErrKeyNotParsable = errors.New("ttlcache: key type cannot be parsed from text")
+
+// Handler serves a JSON view of a cache for administration and debugging,
+// as returned by HTTPHandler. It answers the following requests:
+//
+//	GET /stats             the counters of Stats along with the number of items and bytes
+//	GET /keys              the keys of the live items
+//	GET /item?key=...      the data and deadline of a live item, without extending its life
+//	DELETE /item?key=...   deletes an item, if AllowMutations is set
+//	POST /flush            deletes all items, if AllowMutations is set
+//
+// Keys in queries must be strings, integers or implement encoding.TextUnmarshaler
+type Handler[K comparable, V any] struct {
+	// AllowMutations enables the DELETE /item and POST /flush requests,
+	// which are answered with 403 Forbidden otherwise. It must be set before
+	// the handler is used
+	AllowMutations bool
+
+	cache *Cache[K, V]
+}
+
+// handlerStats is the body of GET /stats
+type handlerStats struct {
+	Hits         uint64 `json:"hits"`
+	Misses       uint64 `json:"misses"`
+	Evictions    uint64 `json:"evictions"`
+	Expired      uint64 `json:"expired"`
+	PeakItems    int    `json:"peakItems"`
+	PeakBytes    int64  `json:"peakBytes"`
+	SlowCleanups uint64 `json:"slowCleanups"`
+	Items        int    `json:"items"`
+	Bytes        int64  `json:"bytes"`
+}
+
+// handlerItem is the body of GET /item, ExpiresAt is omitted for items that never expire
+type handlerItem[K comparable, V any] struct {
+	Key       K          `json:"key"`
+	Data      V          `json:"data"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// HTTPHandler returns a handler serving the stats, keys and items of the cache
+// as JSON, typically mounted under a debug path with http.StripPrefix
+func (cache *Cache[K, V]) HTTPHandler() *Handler[K, V] {
+	return &Handler[K, V]{cache: cache}
+}
+
+// ServeHTTP implements http.Handler
+func (handler *Handler[K, V]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cache := handler.cache
+	switch r.URL.Path {
+	case "/stats":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		stats := cache.Stats()
+		writeJSON(w, handlerStats{
+			Hits:         stats.Hits,
+			Misses:       stats.Misses,
+			Evictions:    stats.Evictions,
+			Expired:      stats.Expired,
+			PeakItems:    stats.PeakItems,
+			PeakBytes:    stats.PeakBytes,
+			SlowCleanups: stats.SlowCleanups,
+			Items:        cache.CountLive(),
+			Bytes:        cache.SizeBytes(),
+		})
+	case "/keys":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, cache.Keys())
+	case "/item":
+		if !allowMethod(w, r, http.MethodGet, http.MethodDelete) {
+			return
+		}
+		if r.Method == http.MethodDelete && !handler.allowMutations(w) {
+			return
+		}
+		key, err := parseKey[K](r.URL.Query().Get("key"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			if _, existed := cache.GetAndDelete(key); !existed {
+				http.NotFound(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		data, expiresAt, found := cache.GetWithExpiration(key)
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		item := handlerItem[K, V]{Key: key, Data: data}
+		if !expiresAt.IsZero() {
+			item.ExpiresAt = &expiresAt
+		}
+		writeJSON(w, item)
+	case "/flush":
+		if !allowMethod(w, r, http.MethodPost) || !handler.allowMutations(w) {
+			return
+		}
+		cache.Flush()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// allowMutations answers 403 Forbidden unless AllowMutations is set
+func (handler *Handler[K, V]) allowMutations(w http.ResponseWriter) bool {
+	if !handler.AllowMutations {
+		http.Error(w, "ttlcache: mutations are not allowed", http.StatusForbidden)
+	}
+	return handler.AllowMutations
+}
+
+// allowMethod answers 405 Method Not Allowed unless the request uses one of methods
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	for _, method := range methods {
+		w.Header().Add("Allow", method)
+	}
+	http.Error(w, "ttlcache: method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// parseKey decodes a key from its text in a query
+func parseKey[K comparable](text string) (K, error) {
+	var key K
+	if unmarshaler, ok := any(&key).(encoding.TextUnmarshaler); ok {
+		err := unmarshaler.UnmarshalText([]byte(text))
+		return key, err
+	}
+	value := reflect.ValueOf(&key).Elem()
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(text)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, value.Type().Bits())
+		if err != nil {
+			return key, err
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(text, 10, value.Type().Bits())
+		if err != nil {
+			return key, err
+		}
+		value.SetUint(n)
+	default:
+		return key, ErrKeyNotParsable
+	}
+	return key, nil
+}
@@ -0,0 +1,144 @@
+package ttlcache
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	cache.Set("a", "1")
+	cache.SetWithTTL("forever", "2", -1)
+	cache.Get("a")
+	cache.Get("missing")
+	server := httptest.NewServer(cache.HTTPHandler())
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/stats")
+	if err != nil {
+		t.Fatalf("Expected /stats to be served, got %v", err)
+	}
+	var stats map[string]interface{}
+	err = json.NewDecoder(response.Body).Decode(&stats)
+	response.Body.Close()
+	if err != nil {
+		t.Fatalf("Expected /stats to return JSON, got %v", err)
+	}
+	for _, field := range []string{"hits", "misses", "evictions", "expired", "peakItems", "peakBytes", "slowCleanups", "items", "bytes"} {
+		if _, ok := stats[field]; !ok {
+			t.Errorf("Expected /stats to hold `%s`, got %v", field, stats)
+		}
+	}
+	if stats["hits"] != 1.0 || stats["misses"] != 1.0 || stats["items"] != 2.0 {
+		t.Errorf("Expected /stats to report 1 hit, 1 miss and 2 items, got %v", stats)
+	}
+
+	response, err = http.Get(server.URL + "/keys")
+	if err != nil {
+		t.Fatalf("Expected /keys to be served, got %v", err)
+	}
+	var keys []string
+	json.NewDecoder(response.Body).Decode(&keys)
+	response.Body.Close()
+	if len(keys) != 2 {
+		t.Errorf("Expected /keys to list both keys, got %v", keys)
+	}
+
+	response, err = http.Get(server.URL + "/item?key=a")
+	if err != nil {
+		t.Fatalf("Expected /item to be served, got %v", err)
+	}
+	var item struct {
+		Key       string
+		Data      string
+		ExpiresAt *time.Time
+	}
+	json.NewDecoder(response.Body).Decode(&item)
+	response.Body.Close()
+	if item.Key != "a" || item.Data != "1" || item.ExpiresAt == nil || item.ExpiresAt.Before(time.Now()) {
+		t.Errorf("Expected /item to return the data and deadline, got %+v", item)
+	}
+	response, _ = http.Get(server.URL + "/item?key=forever")
+	item.ExpiresAt = nil
+	json.NewDecoder(response.Body).Decode(&item)
+	response.Body.Close()
+	if item.Data != "2" || item.ExpiresAt != nil {
+		t.Errorf("Expected /item to omit the deadline of an item that never expires, got %+v", item)
+	}
+
+	response, _ = http.Get(server.URL + "/item?key=missing")
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected /item to return 404 for a missing key, got %d", response.StatusCode)
+	}
+}
+
+func TestHTTPHandlerMutations(t *testing.T) {
+	cache := New[int, string](time.Minute)
+	defer cache.Close()
+	cache.Set(1, "one")
+	cache.Set(2, "two")
+	handler := cache.HTTPHandler()
+
+	serve := func(method, target string) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+		return recorder.Code
+	}
+
+	if code := serve(http.MethodDelete, "/item?key=1"); code != http.StatusForbidden {
+		t.Errorf("Expected DELETE /item to be forbidden by default, got %d", code)
+	}
+	if code := serve(http.MethodPost, "/flush"); code != http.StatusForbidden {
+		t.Errorf("Expected POST /flush to be forbidden by default, got %d", code)
+	}
+	if code := serve(http.MethodGet, "/flush"); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected GET /flush to not be allowed, got %d", code)
+	}
+	if cache.Count() != 2 {
+		t.Errorf("Expected no item to be removed without AllowMutations")
+	}
+
+	handler.AllowMutations = true
+	if code := serve(http.MethodGet, "/item?key=one"); code != http.StatusBadRequest {
+		t.Errorf("Expected a key that does not parse to be rejected, got %d", code)
+	}
+	if code := serve(http.MethodDelete, "/item?key=1"); code != http.StatusNoContent {
+		t.Errorf("Expected DELETE /item to delete the item, got %d", code)
+	}
+	if _, exists := cache.Get(1); exists {
+		t.Errorf("Expected the deleted item to be gone")
+	}
+	if code := serve(http.MethodDelete, "/item?key=1"); code != http.StatusNotFound {
+		t.Errorf("Expected DELETE /item to return 404 for a missing key, got %d", code)
+	}
+	if code := serve(http.MethodPost, "/flush"); code != http.StatusNoContent {
+		t.Errorf("Expected POST /flush to flush the cache, got %d", code)
+	}
+	if cache.Count() != 0 {
+		t.Errorf("Expected the cache to be empty after POST /flush")
+	}
+}
+
+func TestHTTPHandlerClosed(t *testing.T) {
+	cache := NewCache(time.Minute)
+	cache.Set("a", "1")
+	cache.Close()
+	server := httptest.NewServer(cache.HTTPHandler())
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/keys")
+	if err != nil {
+		t.Fatalf("Expected /keys to be served, got %v", err)
+	}
+	var keys []string
+	err = json.NewDecoder(response.Body).Decode(&keys)
+	response.Body.Close()
+	if err != nil || len(keys) != 0 {
+		t.Errorf("Expected /keys of a closed cache to be empty, got %v, %v", keys, err)
+	}
+}
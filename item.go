@@ -2,30 +2,139 @@ package ttlcache
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Item represents a record in the cache map
-type Item struct {
+//
+// Lookups run concurrently under the read lock of the cache and touch the items
+// they find, so expires is guarded by the mutex of the item: it is only written
+// by touch and only read by expired, deadline and the copy made by updated. The
+// other fields are set before the item is stored and never change afterwards,
+// except index, which the expiry queue moves with the cache mutex held for
+// writing, and accesses, which is atomic
+type Item[V any] struct {
+	// accesses counts the hits of the item, it is updated atomically and
+	// comes first to stay 64-bit aligned
+	accesses uint64
 	sync.RWMutex
-	data    string
+	data    V
+	ttl     time.Duration
 	expires *time.Time
+	size    int64
+	// weight is the size given by SetWithWeight, which replaces the size of the data
+	weight  int64
+	index   int
+	version uint64
+	jitter  time.Duration
+	// written is the deadline set by the write ttl, which touches do not move
+	written time.Time
+	// fixed is the deadline given by SetWithDeadline, which updates of the item keep
+	fixed time.Time
+	// created is when the item was set, touches never extend it beyond the max lifetime
+	created time.Time
+	tags    []string
 }
 
-func (item *Item) touch(duration time.Duration) {
+// ItemInfo describes a live item, as returned by Entries and GetEntry
+// Items that never expire have a zero ExpiresAt
+type ItemInfo[V any] struct {
+	Data        V
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+	AccessCount uint64
+}
+
+// touch extends the item by duration from now, a negative duration makes it
+// never expire, which is kept as a zero expiration
+func (item *Item[V]) touch(now time.Time, duration time.Duration) {
+	var expiration time.Time
+	if duration >= 0 {
+		expiration = now.Add(duration)
+	}
 	item.Lock()
-	expiration := time.Now().Add(duration)
 	item.expires = &expiration
 	item.Unlock()
 }
 
-func (item *Item) expired() bool {
+// info describes the item with data, a copy of its data or the data itself
+func (item *Item[V]) info(data V) ItemInfo[V] {
+	return ItemInfo[V]{
+		Data:        data,
+		ExpiresAt:   item.deadline(),
+		CreatedAt:   item.created,
+		AccessCount: atomic.LoadUint64(&item.accesses),
+	}
+}
+
+// updated returns a copy of the item holding data, with the same deadline,
+// weight, tags and access count
+func (item *Item[V]) updated(data V) *Item[V] {
+	item.RLock()
+	defer item.RUnlock()
+	updated := &Item[V]{
+		accesses: atomic.LoadUint64(&item.accesses),
+		data:     data,
+		ttl:      item.ttl,
+		weight:   item.weight,
+		jitter:   item.jitter,
+		written:  item.written,
+		fixed:    item.fixed,
+		created:  item.created,
+		tags:     item.tags,
+	}
+	if item.expires != nil {
+		expiration := *item.expires
+		updated.expires = &expiration
+	}
+	return updated
+}
+
+// pin fixes a deadline of the item before it is stored, which touches cannot move,
+// unless the item already expires earlier. A zero deadline leaves the item as it is
+func (item *Item[V]) pin(deadline time.Time) {
+	if deadline.IsZero() {
+		return
+	}
+	item.fixed = deadline
+	if item.written.IsZero() || deadline.Before(item.written) {
+		item.written = deadline
+	}
+}
+
+// accessed counts a hit of the item
+func (item *Item[V]) accessed() {
+	atomic.AddUint64(&item.accesses, 1)
+}
+
+func (item *Item[V]) expired(now time.Time) bool {
 	var value bool
 	item.RLock()
-	if item.expires == nil {
+	if !item.written.IsZero() && item.written.Before(now) {
+		value = true
+	} else if item.ttl < 0 {
+		value = false
+	} else if item.expires == nil {
 		value = true
+	} else if item.expires.IsZero() {
+		value = false
 	} else {
-		value = item.expires.Before(time.Now())
+		value = item.expires.Before(now)
+	}
+	item.RUnlock()
+	return value
+}
+
+// deadline returns when the item expires, or the zero time if it never does
+func (item *Item[V]) deadline() time.Time {
+	var value time.Time
+	item.RLock()
+	if item.ttl >= 0 && item.expires != nil {
+		value = *item.expires
+	}
+	if !item.written.IsZero() && (value.IsZero() || item.written.Before(value)) {
+		value = item.written
 	}
 	item.RUnlock()
 	return value
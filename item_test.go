@@ -6,28 +6,28 @@ import (
 )
 
 func TestExpired(t *testing.T) {
-	item := &Item{data: "blahblah"}
-	if !item.expired() {
+	item := &Item[string]{data: "blahblah"}
+	if !item.expired(time.Now()) {
 		t.Errorf("Expected item to be expired by default")
 	}
 
 	expiration := time.Now().Add(time.Second)
 	item.expires = &expiration
-	if item.expired() {
+	if item.expired(time.Now()) {
 		t.Errorf("Expected item to not be expired")
 	}
 
 	expiration = time.Now().Add(0 - time.Second)
 	item.expires = &expiration
-	if !item.expired() {
+	if !item.expired(time.Now()) {
 		t.Errorf("Expected item to be expired once time has passed")
 	}
 }
 
 func TestTouch(t *testing.T) {
-	item := &Item{data: "blahblah"}
-	item.touch(time.Second)
-	if item.expired() {
+	item := &Item[string]{data: "blahblah"}
+	item.touch(time.Now(), time.Second)
+	if item.expired(time.Now()) {
 		t.Errorf("Expected item to not be expired once touched")
 	}
 }
@@ -0,0 +1,72 @@
+package ttlcache
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+// ErrNotText is returned by SetJSON and GetJSON when the cache values are
+// neither strings nor byte slices
+var ErrNotText = errors.New("ttlcache: value type does not hold text")
+
+// MarshalJSON serializes the live items of the cache as a JSON object of keys to data
+// Keys must be strings, integers or implement encoding.TextMarshaler
+// A closed cache holds no items and serializes as an empty object
+func (cache *Cache[K, V]) MarshalJSON() ([]byte, error) {
+	cache.mutex.RLock()
+	items := make(map[K]V, len(cache.items))
+	for key, item := range cache.items {
+		if !cache.expired(item) {
+			items[key] = item.data
+		}
+	}
+	cache.mutex.RUnlock()
+	return json.Marshal(items)
+}
+
+// SetJSON stores v encoded as JSON, for caches holding strings or byte slices
+func (cache *Cache[K, V]) SetJSON(key K, v interface{}) error {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var data V
+	value := reflect.ValueOf(&data).Elem()
+	switch {
+	case value.Kind() == reflect.String:
+		value.SetString(string(encoded))
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8:
+		value.SetBytes(encoded)
+	default:
+		return ErrNotText
+	}
+	if cache.Closed() {
+		return ErrClosed
+	}
+	cache.Set(key, data)
+	return nil
+}
+
+// GetJSON looks up an item stored with SetJSON and decodes it into dest
+// A missing item returns false and no error, data that does not decode returns the error
+func (cache *Cache[K, V]) GetJSON(key K, dest interface{}) (found bool, err error) {
+	if cache.Closed() {
+		return false, ErrClosed
+	}
+	data, found := cache.Get(key)
+	if !found {
+		return false, nil
+	}
+	var encoded []byte
+	value := reflect.ValueOf(&data).Elem()
+	switch {
+	case value.Kind() == reflect.String:
+		encoded = []byte(value.String())
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8:
+		encoded = value.Bytes()
+	default:
+		return true, ErrNotText
+	}
+	return true, json.Unmarshal(encoded, dest)
+}
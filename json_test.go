@@ -0,0 +1,73 @@
+package ttlcache
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	cache := New[string, int](time.Second)
+	defer cache.Close()
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.SetWithTTL("expired", 3, time.Millisecond)
+	<-time.After(10 * time.Millisecond)
+
+	data, err := json.Marshal(cache)
+	if err != nil {
+		t.Fatalf("Expected cache to marshal, got %v", err)
+	}
+
+	var items map[string]int
+	if err := json.Unmarshal(data, &items); err != nil {
+		t.Fatalf("Expected marshaled cache to unmarshal, got %v", err)
+	}
+	if len(items) != 2 || items["a"] != 1 || items["b"] != 2 {
+		t.Errorf("Expected only the live items to be marshaled, got %s", data)
+	}
+}
+
+func TestSetGetJSON(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	type user struct {
+		Name string
+		Tags []string
+	}
+	if err := cache.SetJSON("alice", user{Name: "Alice", Tags: []string{"admin"}}); err != nil {
+		t.Fatalf("Expected SetJSON to succeed, got %v", err)
+	}
+	if data, _ := cache.Get("alice"); data != `{"Name":"Alice","Tags":["admin"]}` {
+		t.Errorf("Expected the JSON to be stored as a string, got %s", data)
+	}
+	var alice user
+	if found, err := cache.GetJSON("alice", &alice); !found || err != nil || alice.Name != "Alice" || alice.Tags[0] != "admin" {
+		t.Errorf("Expected GetJSON to decode the struct, got %+v, %v", alice, err)
+	}
+
+	if err := cache.SetJSON("numbers", []int{1, 2, 3}); err != nil {
+		t.Fatalf("Expected SetJSON to succeed, got %v", err)
+	}
+	var numbers []int
+	if found, err := cache.GetJSON("numbers", &numbers); !found || err != nil || len(numbers) != 3 || numbers[2] != 3 {
+		t.Errorf("Expected GetJSON to decode the slice, got %v, %v", numbers, err)
+	}
+
+	if found, err := cache.GetJSON("missing", &numbers); found || err != nil {
+		t.Errorf("Expected a missing key to return false and no error")
+	}
+
+	cache.Set("broken", "{")
+	if found, err := cache.GetJSON("broken", &numbers); !found || err == nil {
+		t.Errorf("Expected a decode error to surface")
+	}
+
+	ints := New[string, int](time.Second)
+	defer ints.Close()
+	if err := ints.SetJSON("alice", alice); err != ErrNotText {
+		t.Errorf("Expected SetJSON on an int cache to return ErrNotText, got %v", err)
+	}
+}
@@ -0,0 +1,340 @@
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNotFound is returned by a loader to report that a key does not exist,
+// letting the cache remember the miss for the negative ttl
+var ErrNotFound = errors.New("ttlcache: key not found")
+
+// ErrLoaderPanicked is returned to the lookups that waited on a loader invocation
+// which panicked, the panic itself propagating to the lookup that ran the loader
+var ErrLoaderPanicked = errors.New("ttlcache: loader panicked")
+
+// call is an in-flight loader invocation shared by concurrent lookups of a key
+type call[V any] struct {
+	done chan struct{}
+	data V
+	err  error
+}
+
+// GetContext is a thread-safe way to lookup items, abandoned if ctx is done
+// Misses are loaded by the loader registered with SetLoader, if any, whose
+// error is returned
+func (cache *Cache[K, V]) GetContext(ctx context.Context, key K) (data V, found bool, err error) {
+	key = cache.normalize(key)
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	if cache.Closed() {
+		return data, false, ErrClosed
+	}
+	if data, found = cache.lookup(key); found {
+		return
+	}
+	if data, found = cache.fallBack(key); found {
+		return
+	}
+	if loader := cache.registered(); loader != nil {
+		if data, err = cache.compute(ctx, key, loader); err != nil {
+			return data, false, err
+		}
+		found = true
+	}
+	return
+}
+
+// SetLoader registers a loader for the misses of Get and GetContext, which turns
+// the cache into a read-through cache. Loaded items are stored with the ttl
+// returned by loader, following the rules of SetWithTTL, and concurrent misses
+// of a key share a single load as with GetOrCompute. A loader error stores
+// nothing, unless it is ErrNotFound and a negative ttl is set or an error ttl is set
+// A nil loader disables loading, which is the default
+func (cache *Cache[K, V]) SetLoader(loader func(key K) (V, time.Duration, error)) {
+	cache.mutex.Lock()
+	if loader == nil {
+		cache.loader = nil
+	} else {
+		cache.loader = func(ctx context.Context, key K) (V, time.Duration, error) {
+			return loader(key)
+		}
+	}
+	cache.mutex.Unlock()
+}
+
+// registered returns the loader set with SetLoader
+func (cache *Cache[K, V]) registered() func(ctx context.Context, key K) (V, time.Duration, error) {
+	cache.mutex.RLock()
+	loader := cache.loader
+	cache.mutex.RUnlock()
+	return loader
+}
+
+// GetOrCompute is a thread-safe way to lookup an item, computing it with loader if it is missing
+// Concurrent lookups of the same missing key share a single loader invocation.
+// If loader fails nothing is cached, and every waiting lookup receives the error,
+// unless the error is ErrNotFound and a negative ttl is set, or an error ttl is set
+// Once the cache is closed, GetOrCompute returns ErrClosed without invoking loader
+func (cache *Cache[K, V]) GetOrCompute(key K, loader func() (V, error)) (V, error) {
+	return cache.GetOrComputeContext(context.Background(), key, func(context.Context) (V, error) {
+		return loader()
+	})
+}
+
+// GetOrComputeContext is like GetOrCompute, but passes ctx to the loader and stops
+// waiting for an in-flight computation once ctx is done, returning ctx.Err()
+// The loader receives the context of the lookup that started it
+func (cache *Cache[K, V]) GetOrComputeContext(ctx context.Context, key K, loader func(ctx context.Context) (V, error)) (V, error) {
+	key = cache.normalize(key)
+	return cache.compute(ctx, key, func(ctx context.Context, key K) (V, time.Duration, error) {
+		data, err := loader(ctx)
+		return data, 0, err
+	})
+}
+
+// GetOrLoadMany is a thread-safe way to lookup several items at once, loading the
+// missing ones with a single loader invocation. The loader receives every missing
+// key once, in the order of keys, and returns the data it found, which is cached
+// with the default ttl. Keys left out by loader are reported as not found, and
+// remembered as such if a negative ttl is set
+// Keys already being loaded by another GetOrLoadMany or GetOrCompute call are
+// not passed to loader but waited for. This only covers loads that started
+// first, so concurrent calls with overlapping misses may still load a key twice
+// If loader fails nothing is cached, and the hits are returned with the error,
+// which is remembered for every missing key if an error ttl is set. The error
+// remembered for a key is returned along with the other keys, as long as loader
+// does not fail itself
+func (cache *Cache[K, V]) GetOrLoadMany(keys []K, loader func(missing []K) (map[K]V, error)) (map[K]V, error) {
+	if cache.Closed() {
+		return nil, ErrClosed
+	}
+	result := cache.GetMany(keys)
+	var missing []K
+	var cached error
+	owned := map[K]*call[V]{}
+	waiting := map[K]*call[V]{}
+	cache.callsMutex.Lock()
+	for _, key := range keys {
+		key = cache.normalize(key)
+		if _, found := result[key]; found || owned[key] != nil || waiting[key] != nil {
+			continue
+		}
+		if c, exists := cache.calls[key]; exists {
+			waiting[key] = c
+			continue
+		}
+		// another lookup may have stored the item since GetMany above
+		if data, found := cache.Peek(key); found {
+			result[key] = data
+			continue
+		}
+		if failure := cache.failure(key); failure != nil {
+			if !errors.Is(failure, ErrNotFound) {
+				cached = failure
+			}
+			continue
+		}
+		c := &call[V]{done: make(chan struct{})}
+		if cache.calls == nil {
+			cache.calls = map[K]*call[V]{}
+		}
+		cache.calls[key] = c
+		owned[key] = c
+		missing = append(missing, key)
+	}
+	cache.callsMutex.Unlock()
+
+	var err error
+	if len(missing) > 0 {
+		err = cache.loadMany(missing, owned, result, loader)
+	}
+
+	for key, c := range waiting {
+		<-c.done
+		if c.err == nil {
+			result[key] = c.data
+		}
+	}
+	if err == nil {
+		err = cached
+	}
+	return result, err
+}
+
+// loadMany invokes loader for the missing keys of GetOrLoadMany, storing the data
+// it found in result and settling the calls owned for them, even if it panics
+func (cache *Cache[K, V]) loadMany(missing []K, owned map[K]*call[V], result map[K]V, loader func(missing []K) (map[K]V, error)) error {
+	atomic.AddInt64(&cache.inflight, 1)
+	loaded := false
+	defer func() {
+		atomic.AddInt64(&cache.inflight, -1)
+		cache.callsMutex.Lock()
+		for _, key := range missing {
+			delete(cache.calls, key)
+		}
+		cache.callsMutex.Unlock()
+		for _, c := range owned {
+			if !loaded {
+				c.err = ErrLoaderPanicked
+			}
+			close(c.done)
+		}
+	}()
+	data, err := loader(missing)
+	loaded = true
+	for _, key := range missing {
+		c := owned[key]
+		value, found := data[key]
+		switch {
+		case err != nil:
+			c.err = err
+			cache.remember(key, err)
+		case found:
+			c.data = value
+			cache.Set(key, value)
+			result[key] = value
+		default:
+			c.err = ErrNotFound
+			cache.remember(key, ErrNotFound)
+		}
+	}
+	return err
+}
+
+// compute looks up key, loading it with loader if it is missing
+func (cache *Cache[K, V]) compute(ctx context.Context, key K, loader func(ctx context.Context, key K) (V, time.Duration, error)) (V, error) {
+	var zero V
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	if cache.Closed() {
+		return zero, ErrClosed
+	}
+	if data, found := cache.lookup(key); found {
+		return data, nil
+	}
+	if failure := cache.failure(key); failure != nil {
+		return zero, failure
+	}
+
+	cache.callsMutex.Lock()
+	if c, exists := cache.calls[key]; exists {
+		cache.callsMutex.Unlock()
+		select {
+		case <-c.done:
+			return c.data, c.err
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+	// another lookup may have stored the item since the miss above
+	if data, found := cache.Peek(key); found {
+		cache.callsMutex.Unlock()
+		return data, nil
+	}
+	if failure := cache.failure(key); failure != nil {
+		cache.callsMutex.Unlock()
+		return zero, failure
+	}
+	c := &call[V]{done: make(chan struct{})}
+	if cache.calls == nil {
+		cache.calls = map[K]*call[V]{}
+	}
+	cache.calls[key] = c
+	cache.callsMutex.Unlock()
+
+	var ttl time.Duration
+	atomic.AddInt64(&cache.inflight, 1)
+	loaded := false
+	defer func() {
+		if !loaded {
+			// the waiters must not take the zero value for loaded data
+			c.err = ErrLoaderPanicked
+		}
+		atomic.AddInt64(&cache.inflight, -1)
+		cache.callsMutex.Lock()
+		delete(cache.calls, key)
+		cache.callsMutex.Unlock()
+		close(c.done)
+	}()
+	c.data, ttl, c.err = loader(ctx, key)
+	loaded = true
+	if c.err == nil {
+		cache.SetWithTTL(key, c.data, ttl)
+	} else {
+		cache.remember(key, c.err)
+	}
+	return c.data, c.err
+}
+
+// InflightLoads returns the number of loader invocations currently running for
+// Get, GetContext, GetOrCompute and GetOrLoadMany. Lookups waiting on the load
+// of another lookup are not counted, so it stays at one however many lookups
+// of a missing key are coalesced
+func (cache *Cache[K, V]) InflightLoads() int {
+	return int(atomic.LoadInt64(&cache.inflight))
+}
+
+// SetNegativeTTL sets how long a key whose loader returned ErrNotFound is
+// remembered as missing, during which GetOrCompute returns ErrNotFound without
+// invoking the loader again. Storing an item for the key forgets the miss
+// A ttl of 0 disables negative caching, which is the default
+func (cache *Cache[K, V]) SetNegativeTTL(ttl time.Duration) {
+	cache.mutex.Lock()
+	cache.negativeTTL = ttl
+	cache.mutex.Unlock()
+}
+
+// SetErrorTTL sets how long a loader error is remembered for its key, during
+// which the loading lookups return the error without invoking the loader again,
+// so that a failing backend is not hammered; the loader is retried once the ttl
+// lapses. Unlike negative caching, which remembers ErrNotFound as a valid answer
+// for the negative ttl, it covers the other errors but the context ones, and it
+// is best much shorter. Storing an item for the key forgets the error
+// A ttl of 0 disables error caching, which is the default
+func (cache *Cache[K, V]) SetErrorTTL(ttl time.Duration) {
+	cache.mutex.Lock()
+	cache.errorTTL = ttl
+	cache.mutex.Unlock()
+}
+
+// negative is a remembered loader failure
+type negative struct {
+	expires time.Time
+	err     error
+}
+
+// failure returns the error remembered for key, ErrNotFound for a key
+// remembered as not found, or nil if there is none
+func (cache *Cache[K, V]) failure(key K) error {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	if failure, exists := cache.negatives[key]; exists && cache.now().Before(failure.expires) {
+		return failure.err
+	}
+	return nil
+}
+
+// remember records the loader error of key, for the negative ttl if it is
+// ErrNotFound or for the error ttl otherwise
+func (cache *Cache[K, V]) remember(key K, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	cache.mutex.Lock()
+	ttl := cache.errorTTL
+	if errors.Is(err, ErrNotFound) {
+		ttl = cache.negativeTTL
+	}
+	if !cache.closed && ttl > 0 {
+		if cache.negatives == nil {
+			cache.negatives = map[K]negative{}
+		}
+		cache.negatives[key] = negative{expires: cache.now().Add(ttl), err: err}
+	}
+	cache.mutex.Unlock()
+}
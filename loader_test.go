@@ -0,0 +1,466 @@
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrCompute(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	var calls int32
+	loader := func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-time.After(50 * time.Millisecond)
+		return "world", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := cache.GetOrCompute("hello", loader)
+			if err != nil || data != "world" {
+				t.Errorf("Expected GetOrCompute to return `world` for `hello`")
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("Expected loader to run exactly once, ran %d times", calls)
+	}
+
+	data, exists := cache.Get("hello")
+	if !exists || data != "world" {
+		t.Errorf("Expected GetOrCompute to cache `world` for `hello`")
+	}
+}
+
+func TestGetOrComputeError(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	failure := errors.New("failure")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.GetOrCompute("hello", func() (string, error) {
+				<-time.After(50 * time.Millisecond)
+				return "", failure
+			})
+			if err != failure {
+				t.Errorf("Expected GetOrCompute to return the loader error")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, exists := cache.Get("hello"); exists {
+		t.Errorf("Expected a failed load to cache nothing")
+	}
+}
+
+func TestGetContext(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.Set("hello", "world")
+
+	data, found, err := cache.GetContext(context.Background(), "hello")
+	if err != nil || !found || data != "world" {
+		t.Errorf("Expected GetContext to return `world` for `hello`")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, found, err := cache.GetContext(ctx, "hello"); err != context.Canceled || found {
+		t.Errorf("Expected GetContext to return the context error once cancelled")
+	}
+}
+
+func TestGetOrComputeContextCancel(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go cache.GetOrComputeContext(context.Background(), "hello", func(ctx context.Context) (string, error) {
+		close(started)
+		<-release
+		return "world", nil
+	})
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-time.After(50 * time.Millisecond)
+		cancel()
+	}()
+
+	begin := time.Now()
+	_, err := cache.GetOrComputeContext(ctx, "hello", func(ctx context.Context) (string, error) {
+		t.Errorf("Expected the in-flight loader to be shared")
+		return "", nil
+	})
+	if err != context.Canceled {
+		t.Errorf("Expected the waiter to return the context error, got %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the waiter to return promptly, took %s", elapsed)
+	}
+	close(release)
+}
+
+func TestGetOrComputeContextLoader(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := cache.GetOrComputeContext(ctx, "hello", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected the loader to observe the context deadline, got %v", err)
+	}
+	if _, exists := cache.Get("hello"); exists {
+		t.Errorf("Expected a cancelled load to cache nothing")
+	}
+}
+
+func TestNegativeCaching(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.SetNegativeTTL(100 * time.Millisecond)
+
+	var calls int32
+	loader := func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "", ErrNotFound
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := cache.GetOrCompute("missing", loader); err != ErrNotFound {
+			t.Errorf("Expected GetOrCompute to return ErrNotFound, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected the loader to run once during the negative ttl, ran %d times", calls)
+	}
+
+	<-time.After(150 * time.Millisecond)
+	if _, err := cache.GetOrCompute("missing", loader); err != ErrNotFound {
+		t.Errorf("Expected GetOrCompute to return ErrNotFound, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected the loader to run again once the negative ttl lapsed, ran %d times", calls)
+	}
+
+	cache.Set("missing", "found")
+	data, err := cache.GetOrCompute("missing", loader)
+	if err != nil || data != "found" {
+		t.Errorf("Expected storing an item to forget the miss")
+	}
+}
+
+func TestErrorCaching(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Hour, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.SetErrorTTL(time.Second)
+
+	failure := errors.New("backend down")
+	var calls int64
+	cache.SetLoader(func(key string) (string, time.Duration, error) {
+		atomic.AddInt64(&calls, 1)
+		if key == "missing" {
+			return "", 0, ErrNotFound
+		}
+		return "", 0, failure
+	})
+
+	for i := 0; i < 5; i++ {
+		if _, found, err := cache.GetContext(context.Background(), "flapping"); found || err != failure {
+			t.Errorf("Expected the cached error to be returned, got %v", err)
+		}
+	}
+	if _, err := cache.GetOrLoadMany([]string{"flapping"}, func(missing []string) (map[string]string, error) {
+		t.Errorf("Expected keys with a cached error to not be loaded, got %v", missing)
+		return nil, nil
+	}); err != failure {
+		t.Errorf("Expected GetOrLoadMany to return the cached error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the loader to run once during the error ttl, ran %d times", calls)
+	}
+
+	clock.Advance(2 * time.Second)
+	if _, found := cache.Get("flapping"); found {
+		t.Errorf("Expected the retried load to fail again")
+	}
+	if calls != 2 {
+		t.Errorf("Expected the loader to be retried once the error ttl lapsed, ran %d times", calls)
+	}
+
+	// not found is left to negative caching, which is disabled
+	cache.Get("missing")
+	cache.Get("missing")
+	if calls != 4 {
+		t.Errorf("Expected ErrNotFound to not be cached as an error, ran %d times", calls)
+	}
+
+	cache.Set("flapping", "recovered")
+	if data, found := cache.Get("flapping"); !found || data != "recovered" {
+		t.Errorf("Expected storing an item to forget the error, got %q", data)
+	}
+}
+
+func TestSetLoader(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Hour, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	var calls int64
+	release := make(chan struct{})
+	cache.SetLoader(func(key string) (string, time.Duration, error) {
+		atomic.AddInt64(&calls, 1)
+		<-release
+		if key == "missing" {
+			return "", 0, errors.New("lookup failed")
+		}
+		return "loaded " + key, time.Minute, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if data, found := cache.Get("hello"); !found || data != "loaded hello" {
+				t.Errorf("Expected a miss to be loaded, got %q", data)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	cache.Get("hello")
+	if calls != 1 {
+		t.Errorf("Expected the loader to be invoked once, got %d", calls)
+	}
+	if remaining, _ := cache.TTLRemaining("hello"); remaining != time.Minute {
+		t.Errorf("Expected the item to be stored with the loaded ttl, got %v", remaining)
+	}
+
+	if _, found, err := cache.GetContext(context.Background(), "missing"); found || err == nil || err.Error() != "lookup failed" {
+		t.Errorf("Expected the loader error to be returned, got %v", err)
+	}
+	if _, found := cache.Get("missing"); found {
+		t.Errorf("Expected a failed load to miss")
+	}
+	if _, exists := cache.Peek("missing"); exists {
+		t.Errorf("Expected a failed load to store nothing")
+	}
+
+	cache.SetLoader(nil)
+	if _, found := cache.Get("other"); found {
+		t.Errorf("Expected misses to not be loaded once the loader is removed")
+	}
+}
+
+func TestGetOrLoadMany(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+
+	var requested []string
+	loader := func(missing []string) (map[string]string, error) {
+		requested = append(requested, missing...)
+		return map[string]string{"c": "3"}, nil
+	}
+	result, err := cache.GetOrLoadMany([]string{"a", "c", "d", "c", "b"}, loader)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if len(requested) != 2 || requested[0] != "c" || requested[1] != "d" {
+		t.Errorf("Expected the loader to receive exactly the missing keys, got %v", requested)
+	}
+	if len(result) != 3 || result["a"] != "1" || result["b"] != "2" || result["c"] != "3" {
+		t.Errorf("Expected the hits and loaded items to be merged, got %v", result)
+	}
+	if data, found := cache.Get("c"); !found || data != "3" {
+		t.Errorf("Expected the loaded item to be cached")
+	}
+
+	requested = nil
+	cache.GetOrLoadMany([]string{"a", "c"}, loader)
+	if len(requested) != 0 {
+		t.Errorf("Expected the loader to not run without misses, got %v", requested)
+	}
+
+	failure := errors.New("failure")
+	result, err = cache.GetOrLoadMany([]string{"a", "e"}, func([]string) (map[string]string, error) {
+		return map[string]string{"e": "5"}, failure
+	})
+	if err != failure || len(result) != 1 || result["a"] != "1" {
+		t.Errorf("Expected the hits to be returned with the loader error, got %v and %v", result, err)
+	}
+	if _, found := cache.Get("e"); found {
+		t.Errorf("Expected nothing to be cached when the loader fails")
+	}
+}
+
+func TestInflightLoads(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	cache.SetLoader(func(key string) (string, time.Duration, error) {
+		once.Do(func() { close(started) })
+		<-release
+		return "world", 0, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Get("hello")
+		}()
+	}
+	<-started
+	<-time.After(20 * time.Millisecond)
+	if inflight := cache.InflightLoads(); inflight != 1 {
+		t.Errorf("Expected exactly one in-flight load, got %d", inflight)
+	}
+	close(release)
+	wg.Wait()
+	if inflight := cache.InflightLoads(); inflight != 0 {
+		t.Errorf("Expected no in-flight load once it finished, got %d", inflight)
+	}
+}
+
+func TestGetOrComputePanic(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	panicked := make(chan interface{})
+	go func() {
+		defer func() { panicked <- recover() }()
+		cache.GetOrCompute("hello", func() (string, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+	waited := make(chan error)
+	go func() {
+		_, err := cache.GetOrCompute("hello", func() (string, error) {
+			return "world", nil
+		})
+		waited <- err
+	}()
+	<-time.After(20 * time.Millisecond)
+	close(release)
+	if recovered := <-panicked; recovered != "boom" {
+		t.Errorf("Expected the panic to reach the lookup running the loader, got %v", recovered)
+	}
+	if err := <-waited; err != ErrLoaderPanicked {
+		t.Errorf("Expected the waiting lookup to get ErrLoaderPanicked, got %v", err)
+	}
+	if inflight := cache.InflightLoads(); inflight != 0 {
+		t.Errorf("Expected no in-flight load after the panic, got %d", inflight)
+	}
+	if data, err := cache.GetOrCompute("hello", func() (string, error) {
+		return "world", nil
+	}); err != nil || data != "world" {
+		t.Errorf("Expected the key to be loaded again after the panic, got %q, %v", data, err)
+	}
+}
+
+func TestGetOrLoadManyPanic(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != "boom" {
+				t.Errorf("Expected the panic to propagate, got %v", recovered)
+			}
+		}()
+		cache.GetOrLoadMany([]string{"a", "b"}, func(missing []string) (map[string]string, error) {
+			panic("boom")
+		})
+	}()
+	if inflight := cache.InflightLoads(); inflight != 0 {
+		t.Errorf("Expected no in-flight load after the panic, got %d", inflight)
+	}
+	result, err := cache.GetOrLoadMany([]string{"a", "b"}, func(missing []string) (map[string]string, error) {
+		return map[string]string{"a": "1", "b": "2"}, nil
+	})
+	if err != nil || len(result) != 2 {
+		t.Errorf("Expected the keys to be loaded again after the panic, got %v, %v", result, err)
+	}
+}
+
+func TestGetOrComputeParallelKeys(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	// each loader only returns once both are running, which a serialized load never reaches
+	var started sync.WaitGroup
+	started.Add(2)
+	loader := func() (string, error) {
+		started.Done()
+		running := make(chan struct{})
+		go func() {
+			started.Wait()
+			close(running)
+		}()
+		select {
+		case <-running:
+			<-time.After(100 * time.Millisecond)
+			return "value", nil
+		case <-time.After(time.Second):
+			return "", errors.New("loads were serialized")
+		}
+	}
+
+	begin := time.Now()
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			if _, err := cache.GetOrCompute(key, loader); err != nil {
+				t.Errorf("Expected `%s` to load in parallel with the other key: %v", key, err)
+			}
+		}(key)
+	}
+	wg.Wait()
+	if elapsed := time.Since(begin); elapsed >= 200*time.Millisecond {
+		t.Errorf("Expected the loads to take the time of one, took %s", elapsed)
+	}
+	cache.callsMutex.Lock()
+	if pending := len(cache.calls); pending != 0 {
+		t.Errorf("Expected the finished loads to be forgotten, %d left", pending)
+	}
+	cache.callsMutex.Unlock()
+}
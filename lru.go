@@ -0,0 +1,261 @@
+package ttlcache
+
+import "unsafe"
+
+// SetMaxItems caps the number of items in the cache, evicting the least
+// recently used items, or those chosen by the policy set with SetPolicy,
+// once the cap is exceeded
+// Lookups of an unbounded cache do not track usage, so until a cap is set
+// items are ordered by insertion
+// A max of 0 means unlimited, which is the default
+func (cache *Cache[K, V]) SetMaxItems(max int) {
+	cache.mutex.Lock()
+	cache.maxItems = max
+	evictions := cache.evictOverflow(nil)
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+}
+
+// SetMaxBytes caps the total size of the values in the cache, evicting the
+// least recently used items, or those chosen by the policy, once the cap is exceeded
+// Items stored with SetWithWeight count their weight instead of their size
+// A value larger than the cap on its own is rejected, leaving its key without an
+// item, and the item it would have replaced is reported as Deleted
+// A max of 0 means unlimited, which is the default
+func (cache *Cache[K, V]) SetMaxBytes(max int64) {
+	cache.mutex.Lock()
+	cache.maxBytes = max
+	evictions := cache.evictOverflow(nil)
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+}
+
+// SetMaxValueSize caps the size of a single value, as measured for SetMaxBytes
+// but ignoring weights, independently of the total size of the cache. Storing a
+// larger value is ignored, leaving its key without an item as with SetMaxBytes,
+// while TrySet reports it by returning false and leaves the key as it is
+// A max of 0 means unlimited, which is the default
+func (cache *Cache[K, V]) SetMaxValueSize(max int64) {
+	cache.mutex.Lock()
+	cache.maxValueSize = max
+	cache.mutex.Unlock()
+}
+
+// tooLarge reports whether data exceeds the max value size,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) tooLarge(data V) bool {
+	return cache.maxValueSize > 0 && cache.sizeOf(data) > cache.maxValueSize
+}
+
+// SetPolicy replaces the eviction policy, a nil policy restores the default LRU policy
+// Items already in the cache are handed to the new policy in no particular order,
+// so the policy is best set before the cache is filled
+func (cache *Cache[K, V]) SetPolicy(policy Policy[K]) {
+	if policy == nil {
+		policy = NewLRUPolicy[K]()
+	}
+	cache.mutex.Lock()
+	cache.policy = policy
+	for key := range cache.items {
+		policy.Added(key)
+	}
+	cache.mutex.Unlock()
+}
+
+// SetWithWeight is a thread-safe way to add new items to the map with a weight,
+// which replaces the size of the value against SetMaxBytes and in SizeBytes
+// It lets callers account for a cost other than memory, such as the work to
+// recompute the value. The weight lasts until the item is set again, which Update
+// and Rename do not count as, and a weight of 0 or less uses the size of the value
+func (cache *Cache[K, V]) SetWithWeight(key K, data V, weight int64) {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return
+	}
+	item := cache.newItem(data, cache.ttlFor(key, data, 0))
+	item.weight = weight
+	evictions := cache.insert(key, item)
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+}
+
+// TrySet is a thread-safe way to store an item in a bounded cache without evicting
+// other items. It returns false if the key has no live item and the cache is
+// full, in which case nothing is stored; live items can always be updated
+func (cache *Cache[K, V]) TrySet(key K, data V) bool {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	if cache.closed || cache.tooLarge(data) {
+		cache.mutex.Unlock()
+		return false
+	}
+	var evictions []EvictionEvent[K, V]
+	if item, exists := cache.items[key]; !exists || cache.expired(item) {
+		if cache.full(data) {
+			// make room from expired items before turning the item away
+			evictions = cache.sweep(evictions, cache.now())
+		}
+		if cache.full(data) {
+			onEvicted := cache.OnEvicted
+			cache.mutex.Unlock()
+			cache.notify(onEvicted, evictions)
+			return false
+		}
+	}
+	evictions = append(evictions, cache.set(key, data, 0)...)
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return true
+}
+
+// full reports whether storing data under a new key would exceed the caps,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) full(data V) bool {
+	return (cache.maxItems > 0 && len(cache.items) >= cache.maxItems) ||
+		(cache.maxBytes > 0 && cache.bytes+cache.sizeOf(data) > cache.maxBytes)
+}
+
+// Capacity returns the caps set with SetMaxItems and SetMaxBytes, 0 meaning unlimited
+func (cache *Cache[K, V]) Capacity() (items int, bytes int64) {
+	cache.mutex.RLock()
+	items, bytes = cache.maxItems, cache.maxBytes
+	cache.mutex.RUnlock()
+	return
+}
+
+// SizeBytes returns the total size of the values in the cache
+func (cache *Cache[K, V]) SizeBytes() int64 {
+	cache.mutex.RLock()
+	bytes := cache.bytes
+	cache.mutex.RUnlock()
+	return bytes
+}
+
+// ItemOverhead is the number of bytes EstimatedMemory counts for the bookkeeping
+// of every item, on top of its key and value: the item itself, its map entry and
+// its place in the expiry queue and the eviction policy. It is a rough figure
+// for 64-bit platforms and may be tuned to match measurements
+var ItemOverhead int64 = 200
+
+// EstimatedMemory returns a rough estimate of the memory held by the items of
+// the cache in bytes: the size of every key and value, as measured for SetMaxBytes
+// but ignoring weights, plus ItemOverhead per item. Keys that are strings count
+// their length and other keys their shallow size
+func (cache *Cache[K, V]) EstimatedMemory() int64 {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	total := int64(len(cache.items)) * ItemOverhead
+	for key, item := range cache.items {
+		if s, ok := keyString(key); ok {
+			total += int64(len(s))
+		} else {
+			total += int64(unsafe.Sizeof(key))
+		}
+		total += cache.sizeOf(item.data)
+	}
+	return total
+}
+
+// sizeOf returns the size in bytes accounted for a value
+func (cache *Cache[K, V]) sizeOf(data V) int64 {
+	if cache.Sizer != nil {
+		return cache.Sizer(data)
+	}
+	switch value := any(data).(type) {
+	case string:
+		return int64(len(value))
+	case []byte:
+		return int64(len(value))
+	}
+	return int64(unsafe.Sizeof(data))
+}
+
+// bounded reports whether the usage of items must be tracked,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) bounded() bool {
+	return cache.maxItems > 0 || cache.maxBytes > 0
+}
+
+// lockForRead takes the read lock, so that concurrent lookups do not serialize,
+// unless lookups must be reported to the policy and therefore need the write lock
+// It returns whether the write lock was taken
+func (cache *Cache[K, V]) lockForRead() (exclusive bool) {
+	cache.mutex.RLock()
+	if !cache.bounded() {
+		return false
+	}
+	cache.mutex.RUnlock()
+	cache.mutex.Lock()
+	return true
+}
+
+// unlockForRead releases the lock taken by lockForRead
+func (cache *Cache[K, V]) unlockForRead(exclusive bool) {
+	if exclusive {
+		cache.mutex.Unlock()
+	} else {
+		cache.mutex.RUnlock()
+	}
+}
+
+// link reports a stored key to the policy,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) link(key K) {
+	if cache.policy == nil {
+		cache.policy = NewLRUPolicy[K]()
+	}
+	cache.policy.Added(key)
+}
+
+// unlink reports a removed key to the policy,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) unlink(key K) {
+	if cache.policy != nil {
+		cache.policy.Removed(key)
+	}
+}
+
+// promote reports a used key to the policy,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) promote(key K) {
+	if cache.policy != nil {
+		cache.policy.Touched(key)
+	}
+}
+
+// evictOverflow removes the items chosen by the policy until the cache is
+// within its caps, it must be called with the cache mutex held
+// Items vetoed by CanEvict are set aside while the policy picks the next
+// candidate, and handed back to it as most recently added afterwards
+func (cache *Cache[K, V]) evictOverflow(evictions []EvictionEvent[K, V]) []EvictionEvent[K, V] {
+	var pinned []K
+	for (cache.maxItems > 0 && len(cache.items) > cache.maxItems) ||
+		(cache.maxBytes > 0 && cache.bytes > cache.maxBytes) {
+		key, ok := cache.policy.Evict()
+		if !ok {
+			break
+		}
+		item, exists := cache.items[key]
+		if !exists {
+			// the policy lost track of the cache, drop the key so it is not chosen again
+			cache.policy.Removed(key)
+			continue
+		}
+		if cache.CanEvict != nil && !cache.CanEvict(key, item.data) {
+			cache.policy.Removed(key)
+			pinned = append(pinned, key)
+			continue
+		}
+		evictions = cache.remove(evictions, key, item, Evicted)
+	}
+	for _, key := range pinned {
+		cache.policy.Added(key)
+	}
+	return evictions
+}
@@ -0,0 +1,314 @@
+package ttlcache
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxItems(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.SetMaxItems(3)
+
+	var evicted []string
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		if reason == Evicted {
+			evicted = append(evicted, key)
+		}
+	}
+
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+	cache.Set("c", "3")
+	cache.Get("a")
+	cache.Set("d", "4")
+
+	if count := cache.Count(); count != 3 {
+		t.Errorf("Expected cache to contain 3 items, got %d", count)
+	}
+	if _, exists := cache.Get("b"); exists {
+		t.Errorf("Expected least recently used `b` to have been evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, exists := cache.Get(key); !exists {
+			t.Errorf("Expected `%s` to not have been evicted", key)
+		}
+	}
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Errorf("Expected only `b` to be reported as evicted, got %v", evicted)
+	}
+}
+
+func TestMaxItemsShrink(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	for i := 0; i < 10; i++ {
+		cache.Set(fmt.Sprintf("key %d", i), "value")
+	}
+	cache.SetMaxItems(4)
+
+	if count := cache.Count(); count != 4 {
+		t.Errorf("Expected cache to shrink to 4 items, got %d", count)
+	}
+	for i := 6; i < 10; i++ {
+		if _, exists := cache.Get(fmt.Sprintf("key %d", i)); !exists {
+			t.Errorf("Expected `key %d` to not have been evicted", i)
+		}
+	}
+
+	cache.SetMaxItems(0)
+	for i := 0; i < 10; i++ {
+		cache.Set(fmt.Sprintf("key %d", i), "value")
+	}
+	if count := cache.Count(); count != 10 {
+		t.Errorf("Expected unlimited cache to hold 10 items, got %d", count)
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.SetMaxBytes(10)
+
+	cache.Set("a", "1234")
+	cache.Set("b", "1234")
+	if size := cache.SizeBytes(); size != 8 {
+		t.Errorf("Expected cache to hold 8 bytes, got %d", size)
+	}
+
+	cache.Set("c", "1234")
+	if size := cache.SizeBytes(); size > 10 {
+		t.Errorf("Expected cache to stay within 10 bytes, got %d", size)
+	}
+	if _, exists := cache.Get("a"); exists {
+		t.Errorf("Expected least recently used `a` to have been evicted")
+	}
+
+	cache.Set("b", "12")
+	cache.Set("d", "123456")
+	if size := cache.SizeBytes(); size != 8 {
+		t.Errorf("Expected cache to hold 8 bytes, got %d", size)
+	}
+	if _, exists := cache.Get("c"); exists {
+		t.Errorf("Expected least recently used `c` to have been evicted")
+	}
+
+	cache.Set("b", "12345678901")
+	if _, exists := cache.Get("b"); exists {
+		t.Errorf("Expected a value larger than the cap to be rejected")
+	}
+	if data, exists := cache.Get("d"); !exists || data != "123456" {
+		t.Errorf("Expected a rejected value to not evict other items")
+	}
+	if size := cache.SizeBytes(); size != 6 {
+		t.Errorf("Expected cache to hold 6 bytes, got %d", size)
+	}
+
+	cache.Delete("d")
+	if size := cache.SizeBytes(); size != 0 {
+		t.Errorf("Expected empty cache to hold 0 bytes, got %d", size)
+	}
+}
+
+func TestCanEvict(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.CanEvict = func(key string, data string) bool {
+		return data != "pinned"
+	}
+	cache.SetMaxItems(3)
+
+	cache.Set("in use", "pinned")
+	for i := 0; i < 10; i++ {
+		cache.Set(fmt.Sprintf("key %d", i), "value")
+	}
+	if _, exists := cache.Get("in use"); !exists {
+		t.Errorf("Expected the pinned item to survive eviction")
+	}
+	if count := cache.Count(); count != 3 {
+		t.Errorf("Expected unpinned items to be evicted down to 3, got %d", count)
+	}
+	for i := 0; i < 8; i++ {
+		if _, exists := cache.Get(fmt.Sprintf("key %d", i)); exists {
+			t.Errorf("Expected `key %d` to have been evicted", i)
+		}
+	}
+}
+
+func TestCanEvictAllPinned(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.CanEvict = func(key string, data string) bool {
+		return false
+	}
+	cache.SetMaxItems(2)
+
+	for i := 0; i < 4; i++ {
+		cache.Set(fmt.Sprintf("key %d", i), "value")
+	}
+	if count := cache.Count(); count != 4 {
+		t.Errorf("Expected the cache to overflow when every item is pinned, got %d items", count)
+	}
+	cache.CanEvict = nil
+	cache.Set("key 4", "value")
+	if count := cache.Count(); count != 2 {
+		t.Errorf("Expected the overflow to be evicted once items are unpinned, got %d items", count)
+	}
+}
+
+func TestTrySet(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.SetMaxItems(2)
+
+	if !cache.TrySet("a", "1") || !cache.TrySet("b", "2") {
+		t.Errorf("Expected new keys to be stored while there is room")
+	}
+	if cache.TrySet("c", "3") {
+		t.Errorf("Expected a full cache to reject a new key")
+	}
+	if _, exists := cache.Get("c"); exists {
+		t.Errorf("Expected the rejected key to not be stored")
+	}
+	if !cache.TrySet("a", "updated") {
+		t.Errorf("Expected a full cache to accept updates of live keys")
+	}
+	if data, _ := cache.Get("a"); data != "updated" {
+		t.Errorf("Expected `a` to have been updated, got %s", data)
+	}
+	if _, exists := cache.Get("b"); !exists {
+		t.Errorf("Expected TrySet to not evict `b`")
+	}
+
+	cache.SetWithTTL("b", "2", 10*time.Millisecond)
+	<-time.After(50 * time.Millisecond)
+	if !cache.TrySet("c", "3") {
+		t.Errorf("Expected expired items to make room for a new key")
+	}
+}
+
+func TestTrySetMaxBytes(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.SetMaxBytes(10)
+
+	if !cache.TrySet("a", "12345678") {
+		t.Errorf("Expected a value within the cap to be stored")
+	}
+	if cache.TrySet("b", "123") {
+		t.Errorf("Expected a value beyond the remaining room to be rejected")
+	}
+	if !cache.TrySet("b", "12") {
+		t.Errorf("Expected a value within the remaining room to be stored")
+	}
+}
+
+func TestSetWithWeight(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.SetMaxBytes(100)
+
+	var evicted []string
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		if reason == Evicted {
+			evicted = append(evicted, key)
+		}
+	}
+	cache.SetWithWeight("cheap 1", "x", 10)
+	cache.SetWithWeight("cheap 2", "x", 10)
+	cache.SetWithWeight("expensive", "x", 70)
+	if size := cache.SizeBytes(); size != 90 {
+		t.Errorf("Expected the cache to weigh 90, got %d", size)
+	}
+
+	cache.SetWithWeight("heavy", "x", 50)
+	if fmt.Sprint(evicted) != "[cheap 1 cheap 2 expensive]" {
+		t.Errorf("Expected the least recently used items to be evicted by weight, got %v", evicted)
+	}
+	cache.Set("light", "12345")
+	if size := cache.SizeBytes(); size != 55 {
+		t.Errorf("Expected an unweighted item to weigh its size, got a total of %d", size)
+	}
+	if count := cache.Count(); count != 2 {
+		t.Errorf("Expected 2 items to fit the budget, got %d", count)
+	}
+	cache.SetWithWeight("too heavy", "x", 101)
+	if _, exists := cache.Get("too heavy"); exists {
+		t.Errorf("Expected an item heavier than the budget to be rejected")
+	}
+}
+
+func TestEstimatedMemory(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key %03d", i), strings.Repeat("v", 1000))
+	}
+	cache.SetWithWeight("weighted", "value", 1<<20)
+	expected := int64(100*(7+1000)+8+5) + 101*ItemOverhead
+	if estimate := cache.EstimatedMemory(); estimate != expected {
+		t.Errorf("Expected an estimate of %d bytes, got %d", expected, estimate)
+	}
+	if estimate := cache.EstimatedMemory(); estimate < 100*1000 || estimate > 2*100*1000 {
+		t.Errorf("Expected the estimate to be close to the size of the values, got %d", estimate)
+	}
+}
+
+func TestMaxValueSize(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.SetMaxValueSize(5)
+
+	cache.Set("at", "12345")
+	if data, found := cache.Get("at"); !found || data != "12345" {
+		t.Errorf("Expected a value at the limit to be stored")
+	}
+	cache.Set("over", "123456")
+	if cache.Has("over") {
+		t.Errorf("Expected a value over the limit to be rejected")
+	}
+	if cache.TrySet("at", "123456") {
+		t.Errorf("Expected TrySet to report a value over the limit")
+	}
+	if data, _ := cache.Get("at"); data != "12345" {
+		t.Errorf("Expected a rejected TrySet to leave the item as it is, got %s", data)
+	}
+	if !cache.TrySet("other", "1234") {
+		t.Errorf("Expected TrySet to store a value under the limit")
+	}
+
+	cache.SetMaxValueSize(0)
+	cache.Set("over", strings.Repeat("x", 1000))
+	if !cache.Has("over") {
+		t.Errorf("Expected a max of 0 to accept values of any size")
+	}
+}
+
+func TestMaxValueSizeExistingKey(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.SetMaxValueSize(5)
+	var events []EvictionReason
+	cache.OnEvicted = func(key string, data string, reason EvictionReason) {
+		events = append(events, reason)
+	}
+
+	cache.Set("key", "12345")
+	wait := cache.WaitExpired("key")
+	cache.Set("key", "123456")
+	if cache.Has("key") {
+		t.Errorf("Expected a value over the limit to leave its key without an item")
+	}
+	if len(events) != 1 || events[0] != Deleted {
+		t.Errorf("Expected the dropped value to be reported as Deleted, got %v", events)
+	}
+	select {
+	case <-wait:
+	default:
+		t.Errorf("Expected dropping the value to unblock the wait")
+	}
+}
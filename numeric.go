@@ -0,0 +1,112 @@
+package ttlcache
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// ErrNotInteger is returned when incrementing an item whose data is not an integer
+var ErrNotInteger = errors.New("ttlcache: value is not an integer")
+
+// ErrOverflow is returned when incrementing an item would overflow its integer type
+var ErrOverflow = errors.New("ttlcache: integer overflow")
+
+// Increment is a thread-safe way to add delta to an integer item, returning the new value
+// Items holding strings are parsed and stored back in base 10, items holding
+// integer types are updated in place. A missing item starts at 0. Either way the
+// life of the item is refreshed, while a live item keeps its tags and access count
+// Non-integer data returns ErrNotInteger and a result that does not fit the
+// integer type, or int64 for strings, returns ErrOverflow, leaving the item as it is
+func (cache *Cache[K, V]) Increment(key K, delta int64) (int64, error) {
+	return cache.add(key, delta, false)
+}
+
+// add adds delta to an integer item, or subtracts it for Decrement, which cannot
+// negate delta since the negation of math.MinInt64 overflows
+func (cache *Cache[K, V]) add(key K, delta int64, subtract bool) (int64, error) {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return 0, ErrClosed
+	}
+	var current int64
+	item, exists := cache.items[key]
+	live := exists && !cache.expired(item)
+	if live {
+		var err error
+		if current, err = toInt64(item.data); err != nil {
+			cache.mutex.Unlock()
+			return 0, err
+		}
+	}
+	if subtract {
+		if (delta < 0 && current > math.MaxInt64+delta) || (delta > 0 && current < math.MinInt64+delta) {
+			cache.mutex.Unlock()
+			return 0, ErrOverflow
+		}
+		current -= delta
+	} else {
+		if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+			cache.mutex.Unlock()
+			return 0, ErrOverflow
+		}
+		current += delta
+	}
+	data, err := fromInt64[V](current)
+	if err != nil {
+		cache.mutex.Unlock()
+		return 0, err
+	}
+	var evictions []EvictionEvent[K, V]
+	if live {
+		evictions = cache.insert(key, cache.renewed(key, item, data))
+	} else {
+		evictions = cache.set(key, data, 0)
+	}
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return current, nil
+}
+
+// Decrement is a thread-safe way to subtract delta from an integer item, returning the new value
+// It follows the same rules as Increment
+func (cache *Cache[K, V]) Decrement(key K, delta int64) (int64, error) {
+	return cache.add(key, delta, true)
+}
+
+// toInt64 reads integer data, or data holding a base 10 integer string
+func toInt64[V any](data V) (int64, error) {
+	value := reflect.ValueOf(&data).Elem()
+	switch value.Kind() {
+	case reflect.String:
+		n, err := strconv.ParseInt(value.String(), 10, 64)
+		if err != nil {
+			return 0, ErrNotInteger
+		}
+		return n, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int(), nil
+	}
+	return 0, ErrNotInteger
+}
+
+// fromInt64 converts n into integer data, or a base 10 integer string
+func fromInt64[V any](n int64) (data V, err error) {
+	value := reflect.ValueOf(&data).Elem()
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(strconv.FormatInt(n, 10))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.OverflowInt(n) {
+			return data, ErrOverflow
+		}
+		value.SetInt(n)
+	default:
+		return data, ErrNotInteger
+	}
+	return data, nil
+}
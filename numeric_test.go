@@ -0,0 +1,104 @@
+package ttlcache
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIncrement(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	if n, err := cache.Increment("counter", 5); err != nil || n != 5 {
+		t.Errorf("Expected a missing counter to start at 0, got %d, %v", n, err)
+	}
+	if n, err := cache.Decrement("counter", 2); err != nil || n != 3 {
+		t.Errorf("Expected Decrement to return 3, got %d, %v", n, err)
+	}
+	if data, _ := cache.Get("counter"); data != "3" {
+		t.Errorf("Expected the counter to be stored as `3`, got %s", data)
+	}
+
+	cache.Set("text", "hello")
+	if _, err := cache.Increment("text", 1); err != ErrNotInteger {
+		t.Errorf("Expected incrementing text to return ErrNotInteger, got %v", err)
+	}
+	if data, _ := cache.Get("text"); data != "hello" {
+		t.Errorf("Expected a failed increment to leave `text` unchanged, got %s", data)
+	}
+}
+
+func TestIncrementConcurrent(t *testing.T) {
+	cache := New[string, int64](time.Second)
+	defer cache.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(delta int64) {
+			defer wg.Done()
+			if _, err := cache.Increment("counter", delta); err != nil {
+				t.Errorf("Expected Increment to succeed, got %v", err)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+
+	if data, _ := cache.Get("counter"); data != 4950 {
+		t.Errorf("Expected the counter to equal the sum of deltas, got %d", data)
+	}
+}
+
+func TestIncrementKeepsTags(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.SetWithTags("views", "1", "page")
+	cache.Get("views")
+	if value, err := cache.Increment("views", 1); err != nil || value != 2 {
+		t.Errorf("Expected `views` to be incremented to 2, got %d (%v)", value, err)
+	}
+	if info, _ := cache.GetEntry("views"); info.AccessCount != 2 {
+		t.Errorf("Expected the access count to be kept, got %d", info.AccessCount)
+	}
+	if invalidated := cache.InvalidateTag("page"); invalidated != 1 || cache.Has("views") {
+		t.Errorf("Expected the incremented item to keep its tags, %d invalidated", invalidated)
+	}
+}
+
+func TestIncrementOverflow(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+
+	cache.Set("max", strconv.FormatInt(math.MaxInt64-1, 10))
+	if value, err := cache.Increment("max", 1); err != nil || value != math.MaxInt64 {
+		t.Errorf("Expected an increment up to the limit to succeed, got %d (%v)", value, err)
+	}
+	if _, err := cache.Increment("max", 1); err != ErrOverflow {
+		t.Errorf("Expected an increment past the limit to return ErrOverflow, got %v", err)
+	}
+	if data, _ := cache.Get("max"); data != strconv.FormatInt(math.MaxInt64, 10) {
+		t.Errorf("Expected an overflowing increment to leave the item as it is, got %s", data)
+	}
+	cache.Set("min", strconv.FormatInt(math.MinInt64, 10))
+	if _, err := cache.Decrement("min", 1); err != ErrOverflow {
+		t.Errorf("Expected a decrement past the limit to return ErrOverflow, got %v", err)
+	}
+	cache.Set("zero", "0")
+	if _, err := cache.Decrement("zero", math.MinInt64); err != ErrOverflow {
+		t.Errorf("Expected decrementing by the smallest int64 to return ErrOverflow, got %v", err)
+	}
+	if value, err := cache.Decrement("min", math.MinInt64); err != nil || value != 0 {
+		t.Errorf("Expected the smallest int64 minus itself to be 0, got %d (%v)", value, err)
+	}
+
+	small := New[string, int8](time.Minute)
+	defer small.Close()
+	small.Set("counter", math.MaxInt8)
+	if _, err := small.Increment("counter", 1); err != ErrOverflow {
+		t.Errorf("Expected an increment past the int8 limit to return ErrOverflow, got %v", err)
+	}
+}
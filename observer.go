@@ -0,0 +1,13 @@
+package ttlcache
+
+import "time"
+
+// Observer receives the duration of cache operations, see Cache.Observer
+// Its methods are called on the goroutine of the operation once it completes,
+// so they must be safe for concurrent use and return quickly
+type Observer interface {
+	// ObserveGet is called after every Get, hit reporting whether it found an item
+	ObserveGet(hit bool, d time.Duration)
+	// ObserveSet is called after every Set and SetWithTTL
+	ObserveSet(d time.Duration)
+}
@@ -0,0 +1,54 @@
+package ttlcache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingObserver struct {
+	sync.Mutex
+	hits, misses, sets int
+	durations          []time.Duration
+}
+
+func (observer *recordingObserver) ObserveGet(hit bool, d time.Duration) {
+	observer.Lock()
+	if hit {
+		observer.hits++
+	} else {
+		observer.misses++
+	}
+	observer.durations = append(observer.durations, d)
+	observer.Unlock()
+}
+
+func (observer *recordingObserver) ObserveSet(d time.Duration) {
+	observer.Lock()
+	observer.sets++
+	observer.durations = append(observer.durations, d)
+	observer.Unlock()
+}
+
+func TestObserver(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	observer := &recordingObserver{}
+	cache.Observer = observer
+
+	cache.Set("hello", "world")
+	cache.SetWithTTL("other", "world", time.Minute)
+	cache.Get("hello")
+	cache.Get("missing")
+
+	observer.Lock()
+	defer observer.Unlock()
+	if observer.hits != 1 || observer.misses != 1 || observer.sets != 2 {
+		t.Errorf("Expected 1 hit, 1 miss and 2 sets, got %d, %d and %d", observer.hits, observer.misses, observer.sets)
+	}
+	for _, d := range observer.durations {
+		if d < 0 || d > time.Second {
+			t.Errorf("Expected a plausible duration, got %s", d)
+		}
+	}
+}
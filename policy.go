@@ -0,0 +1,193 @@
+package ttlcache
+
+import (
+	"container/heap"
+	"container/list"
+	"time"
+)
+
+// Policy decides which item is evicted once a bounded cache exceeds its caps
+// The cache calls every method with its mutex held, so implementations need no locking
+//
+// Added is called when a key is stored, Touched when it is looked up, and
+// Removed when it leaves the cache for any reason. Evict returns the key that
+// should be evicted next, or false if the policy tracks no keys
+type Policy[K comparable] interface {
+	Added(key K)
+	Touched(key K)
+	Removed(key K)
+	Evict() (key K, ok bool)
+}
+
+// NewLRUPolicy returns a policy evicting the least recently used key, which is the default
+func NewLRUPolicy[K comparable]() Policy[K] {
+	return &listPolicy[K]{promote: true}
+}
+
+// NewFIFOPolicy returns a policy evicting the oldest key, regardless of how often it is used
+func NewFIFOPolicy[K comparable]() Policy[K] {
+	return &listPolicy[K]{}
+}
+
+// listPolicy orders keys from the most recently added, or used if promote is set, to the least
+type listPolicy[K comparable] struct {
+	order    list.List
+	elements map[K]*list.Element
+	promote  bool
+}
+
+func (policy *listPolicy[K]) Added(key K) {
+	if policy.elements == nil {
+		policy.elements = map[K]*list.Element{}
+	}
+	if element, exists := policy.elements[key]; exists {
+		policy.order.MoveToFront(element)
+		return
+	}
+	policy.elements[key] = policy.order.PushFront(key)
+}
+
+func (policy *listPolicy[K]) Touched(key K) {
+	if element, exists := policy.elements[key]; exists && policy.promote {
+		policy.order.MoveToFront(element)
+	}
+}
+
+func (policy *listPolicy[K]) Removed(key K) {
+	if element, exists := policy.elements[key]; exists {
+		policy.order.Remove(element)
+		delete(policy.elements, key)
+	}
+}
+
+func (policy *listPolicy[K]) Evict() (key K, ok bool) {
+	element := policy.order.Back()
+	if element == nil {
+		return key, false
+	}
+	return element.Value.(K), true
+}
+
+// NewLFUPolicy returns a policy evicting the least frequently used key, the least
+// recently used one among keys used as often. Keys start with a count of 1 when
+// they are stored, and every lookup adds one
+// Every decay interval, measured on clock or the real time if nil, all counts are
+// halved so that they reflect recent popularity: a key that was popular once is
+// not kept forever over keys in use now. A decay of 0 never halves the counts
+func NewLFUPolicy[K comparable](decay time.Duration, clock Clock) Policy[K] {
+	if clock == nil {
+		clock = realClock{}
+	}
+	return &lfuPolicy[K]{decay: decay, clock: clock}
+}
+
+// lfuPolicy keeps keys in a min-heap ordered by count, then by last use
+type lfuPolicy[K comparable] struct {
+	entries   lfuHeap[K]
+	index     map[K]*lfuEntry[K]
+	seq       uint64
+	decay     time.Duration
+	clock     Clock
+	nextDecay time.Time
+}
+
+type lfuEntry[K comparable] struct {
+	key   K
+	count uint64
+	seq   uint64
+	index int
+}
+
+func (policy *lfuPolicy[K]) Added(key K) {
+	policy.age()
+	if policy.index == nil {
+		policy.index = map[K]*lfuEntry[K]{}
+	}
+	policy.seq++
+	if entry, exists := policy.index[key]; exists {
+		entry.count, entry.seq = 1, policy.seq
+		heap.Fix(&policy.entries, entry.index)
+		return
+	}
+	entry := &lfuEntry[K]{key: key, count: 1, seq: policy.seq}
+	policy.index[key] = entry
+	heap.Push(&policy.entries, entry)
+}
+
+func (policy *lfuPolicy[K]) Touched(key K) {
+	policy.age()
+	if entry, exists := policy.index[key]; exists {
+		policy.seq++
+		entry.count++
+		entry.seq = policy.seq
+		heap.Fix(&policy.entries, entry.index)
+	}
+}
+
+func (policy *lfuPolicy[K]) Removed(key K) {
+	if entry, exists := policy.index[key]; exists {
+		heap.Remove(&policy.entries, entry.index)
+		delete(policy.index, key)
+	}
+}
+
+func (policy *lfuPolicy[K]) Evict() (key K, ok bool) {
+	policy.age()
+	if len(policy.entries) == 0 {
+		return key, false
+	}
+	return policy.entries[0].key, true
+}
+
+// age halves the counts once per elapsed decay interval
+func (policy *lfuPolicy[K]) age() {
+	if policy.decay <= 0 {
+		return
+	}
+	now := policy.clock.Now()
+	if policy.nextDecay.IsZero() {
+		policy.nextDecay = now.Add(policy.decay)
+		return
+	}
+	if now.Before(policy.nextDecay) {
+		return
+	}
+	halvings := 1 + now.Sub(policy.nextDecay)/policy.decay
+	policy.nextDecay = policy.nextDecay.Add(halvings * policy.decay)
+	for _, entry := range policy.entries {
+		entry.count >>= uint64(halvings)
+	}
+	heap.Init(&policy.entries)
+}
+
+// lfuHeap implements heap.Interface over the entries of an lfuPolicy
+type lfuHeap[K comparable] []*lfuEntry[K]
+
+func (entries lfuHeap[K]) Len() int { return len(entries) }
+
+func (entries lfuHeap[K]) Less(i, j int) bool {
+	if entries[i].count != entries[j].count {
+		return entries[i].count < entries[j].count
+	}
+	return entries[i].seq < entries[j].seq
+}
+
+func (entries lfuHeap[K]) Swap(i, j int) {
+	entries[i], entries[j] = entries[j], entries[i]
+	entries[i].index = i
+	entries[j].index = j
+}
+
+func (entries *lfuHeap[K]) Push(x any) {
+	entry := x.(*lfuEntry[K])
+	entry.index = len(*entries)
+	*entries = append(*entries, entry)
+}
+
+func (entries *lfuHeap[K]) Pop() any {
+	old := *entries
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*entries = old[:len(old)-1]
+	return entry
+}
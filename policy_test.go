@@ -0,0 +1,162 @@
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicies(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		policy  Policy[string]
+		evicted string
+	}{
+		{"LRU", NewLRUPolicy[string](), "b"},
+		{"FIFO", NewFIFOPolicy[string](), "a"},
+	} {
+		cache := NewCache(time.Second)
+		cache.SetPolicy(test.policy)
+		cache.SetMaxItems(3)
+
+		cache.Set("a", "1")
+		cache.Set("b", "2")
+		cache.Set("c", "3")
+		cache.Get("a")
+		cache.Set("d", "4")
+
+		if _, exists := cache.Peek(test.evicted); exists {
+			t.Errorf("Expected %s policy to evict `%s`", test.name, test.evicted)
+		}
+		if count := cache.Count(); count != 3 {
+			t.Errorf("Expected %s policy to keep 3 items, got %d", test.name, count)
+		}
+		cache.Close()
+	}
+}
+
+// lastPolicy evicts the most recently added key
+type lastPolicy struct {
+	keys []string
+}
+
+func (policy *lastPolicy) Added(key string) {
+	policy.Removed(key)
+	policy.keys = append(policy.keys, key)
+}
+
+func (policy *lastPolicy) Touched(key string) {}
+
+func (policy *lastPolicy) Removed(key string) {
+	for i, k := range policy.keys {
+		if k == key {
+			policy.keys = append(policy.keys[:i], policy.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+func (policy *lastPolicy) Evict() (string, bool) {
+	if len(policy.keys) == 0 {
+		return "", false
+	}
+	return policy.keys[len(policy.keys)-1], true
+}
+
+func TestCustomPolicy(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	policy := &lastPolicy{}
+	cache.SetPolicy(policy)
+	cache.SetMaxItems(2)
+
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+	cache.Set("c", "3")
+
+	if _, exists := cache.Peek("c"); exists {
+		t.Errorf("Expected the custom policy to evict `c`")
+	}
+	cache.Delete("a")
+	cache.Flush()
+	if len(policy.keys) != 0 {
+		t.Errorf("Expected removed keys to be reported to the policy, got %v", policy.keys)
+	}
+}
+
+func TestSetPolicyExistingItems(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+
+	policy := &lastPolicy{}
+	cache.SetPolicy(policy)
+	if len(policy.keys) != 2 {
+		t.Errorf("Expected existing keys to be handed to the new policy, got %v", policy.keys)
+	}
+	cache.SetMaxItems(1)
+	if count := cache.Count(); count != 1 {
+		t.Errorf("Expected cache to shrink to 1 item, got %d", count)
+	}
+}
+
+func TestLFUPolicyDecay(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		decay   time.Duration
+		evicted string
+	}{
+		{"without decay", 0, "c"},
+		{"with decay", time.Minute, "a"},
+	} {
+		clock := NewFakeClock(time.Now())
+		cache := NewWithConfig[string, string](Config{TTL: time.Hour, Clock: clock})
+		cache.SetCleanupInterval(time.Hour)
+		cache.SetPolicy(NewLFUPolicy[string](test.decay, clock))
+		cache.SetMaxItems(2)
+
+		// an early burst on `a`, followed by sustained use of `b`
+		cache.Set("a", "1")
+		cache.Set("b", "2")
+		for i := 0; i < 100; i++ {
+			cache.Get("a")
+		}
+		for minute := 0; minute < 10; minute++ {
+			for i := 0; i < 5; i++ {
+				cache.Get("b")
+			}
+			clock.Advance(time.Minute)
+		}
+		cache.Set("c", "3")
+
+		if _, exists := cache.Peek(test.evicted); exists {
+			t.Errorf("Expected LFU %s to evict `%s`, got %v", test.name, test.evicted, cache.Keys())
+		}
+		if !cache.Has("b") {
+			t.Errorf("Expected LFU %s to keep `b`", test.name)
+		}
+		cache.Close()
+	}
+}
+
+func TestLFUPolicy(t *testing.T) {
+	cache := NewCache(time.Hour)
+	defer cache.Close()
+	cache.SetPolicy(NewLFUPolicy[string](0, nil))
+	cache.SetMaxItems(3)
+
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+	cache.Set("c", "3")
+	cache.Set("d", "4")
+	if cache.Has("a") {
+		t.Errorf("Expected the oldest of the keys used as often to be evicted, got %v", cache.SortedKeys())
+	}
+	cache.Get("b")
+	cache.Get("b")
+	cache.Get("c")
+	cache.Set("e", "5")
+	if cache.Has("d") || !cache.Has("b") || !cache.Has("c") {
+		t.Errorf("Expected the least frequently used key to be evicted, got %v", cache.SortedKeys())
+	}
+}
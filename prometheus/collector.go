@@ -0,0 +1,60 @@
+// Package prometheus exposes ttlcache statistics as Prometheus metrics
+package prometheus
+
+import (
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/wunderlist/ttlcache"
+)
+
+// Source is the part of a cache read by the Collector
+type Source interface {
+	Stats() ttlcache.Stats
+	Count() int
+	SizeBytes() int64
+}
+
+// Collector is a prometheus.Collector reporting the statistics of a cache
+type Collector struct {
+	cache     Source
+	hits      *prom.Desc
+	misses    *prom.Desc
+	evictions *prom.Desc
+	expired   *prom.Desc
+	items     *prom.Desc
+	bytes     *prom.Desc
+}
+
+// NewCollector returns a Collector for cache, labelling its metrics with the cache name
+func NewCollector(name string, cache Source) *Collector {
+	labels := prom.Labels{"cache": name}
+	return &Collector{
+		cache:     cache,
+		hits:      prom.NewDesc("ttlcache_hits_total", "Number of lookups that found a live item.", nil, labels),
+		misses:    prom.NewDesc("ttlcache_misses_total", "Number of lookups that found no live item.", nil, labels),
+		evictions: prom.NewDesc("ttlcache_evictions_total", "Number of items removed before they expired.", nil, labels),
+		expired:   prom.NewDesc("ttlcache_expired_total", "Number of items removed because they outlived their ttl.", nil, labels),
+		items:     prom.NewDesc("ttlcache_items", "Number of items in the cache.", nil, labels),
+		bytes:     prom.NewDesc("ttlcache_size_bytes", "Total size of the values in the cache.", nil, labels),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (collector *Collector) Describe(ch chan<- *prom.Desc) {
+	ch <- collector.hits
+	ch <- collector.misses
+	ch <- collector.evictions
+	ch <- collector.expired
+	ch <- collector.items
+	ch <- collector.bytes
+}
+
+// Collect implements prometheus.Collector
+func (collector *Collector) Collect(ch chan<- prom.Metric) {
+	stats := collector.cache.Stats()
+	ch <- prom.MustNewConstMetric(collector.hits, prom.CounterValue, float64(stats.Hits))
+	ch <- prom.MustNewConstMetric(collector.misses, prom.CounterValue, float64(stats.Misses))
+	ch <- prom.MustNewConstMetric(collector.evictions, prom.CounterValue, float64(stats.Evictions))
+	ch <- prom.MustNewConstMetric(collector.expired, prom.CounterValue, float64(stats.Expired))
+	ch <- prom.MustNewConstMetric(collector.items, prom.GaugeValue, float64(collector.cache.Count()))
+	ch <- prom.MustNewConstMetric(collector.bytes, prom.GaugeValue, float64(collector.cache.SizeBytes()))
+}
@@ -0,0 +1,46 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/wunderlist/ttlcache"
+)
+
+func TestCollector(t *testing.T) {
+	cache := ttlcache.NewCache(time.Minute)
+	defer cache.Close()
+
+	cache.Set("hello", "world")
+	cache.Set("doomed", "value")
+	cache.Get("hello")
+	cache.Get("hello")
+	cache.Get("missing")
+	cache.Delete("doomed")
+
+	expected := `
+# HELP ttlcache_evictions_total Number of items removed before they expired.
+# TYPE ttlcache_evictions_total counter
+ttlcache_evictions_total{cache="test"} 1
+# HELP ttlcache_expired_total Number of items removed because they outlived their ttl.
+# TYPE ttlcache_expired_total counter
+ttlcache_expired_total{cache="test"} 0
+# HELP ttlcache_hits_total Number of lookups that found a live item.
+# TYPE ttlcache_hits_total counter
+ttlcache_hits_total{cache="test"} 2
+# HELP ttlcache_items Number of items in the cache.
+# TYPE ttlcache_items gauge
+ttlcache_items{cache="test"} 1
+# HELP ttlcache_misses_total Number of lookups that found no live item.
+# TYPE ttlcache_misses_total counter
+ttlcache_misses_total{cache="test"} 1
+# HELP ttlcache_size_bytes Total size of the values in the cache.
+# TYPE ttlcache_size_bytes gauge
+ttlcache_size_bytes{cache="test"} 5
+`
+	if err := testutil.CollectAndCompare(NewCollector("test", cache), strings.NewReader(expected)); err != nil {
+		t.Errorf("Unexpected metrics: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package ttlcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// RefreshAhead makes Get reload an item in the background once its remaining
+// life drops below threshold, serving the current data in the meantime
+// At most one refresh per key is in flight, and a failed refresh leaves the item as it is
+// A refresh whose item was deleted or written again in the meantime is dropped
+// A nil loader disables refreshing, which is the default
+func (cache *Cache[K, V]) RefreshAhead(threshold time.Duration, loader func(key K) (V, error)) {
+	cache.mutex.Lock()
+	cache.refreshThreshold = threshold
+	cache.refreshLoader = loader
+	cache.mutex.Unlock()
+}
+
+// SetMaxConcurrentRefreshes caps the number of background refreshes in flight
+// across all keys, so that a burst of reads near expiry does not overwhelm the
+// loader. Reads that find the cap reached serve the current data without
+// starting a refresh, and are counted by SkippedRefreshes; a later read starts
+// it once a slot is free
+// A max of 0 means unlimited, which is the default
+func (cache *Cache[K, V]) SetMaxConcurrentRefreshes(max int) {
+	cache.refreshMutex.Lock()
+	cache.maxRefreshes = max
+	cache.refreshMutex.Unlock()
+}
+
+// SkippedRefreshes returns the number of refreshes that were not started
+// because the cap set with SetMaxConcurrentRefreshes was reached
+func (cache *Cache[K, V]) SkippedRefreshes() int64 {
+	return atomic.LoadInt64(&cache.skippedRefreshes)
+}
+
+// needsRefresh reports whether an item is close enough to expiry to be reloaded,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) needsRefresh(item *Item[V]) bool {
+	if cache.refreshLoader == nil {
+		return false
+	}
+	deadline := item.deadline()
+	return !deadline.IsZero() && deadline.Sub(cache.now()) < cache.refreshThreshold
+}
+
+// refresh reloads item in the background unless a refresh of key is already
+// in flight or the cap is reached, it must not be called with the cache mutex held
+func (cache *Cache[K, V]) refresh(key K, item *Item[V], loader func(key K) (V, error)) {
+	cache.refreshMutex.Lock()
+	if _, exists := cache.refreshing[key]; exists {
+		cache.refreshMutex.Unlock()
+		return
+	}
+	if cache.maxRefreshes > 0 && len(cache.refreshing) >= cache.maxRefreshes {
+		cache.refreshMutex.Unlock()
+		atomic.AddInt64(&cache.skippedRefreshes, 1)
+		return
+	}
+	if cache.refreshing == nil {
+		cache.refreshing = map[K]struct{}{}
+	}
+	cache.refreshing[key] = struct{}{}
+	cache.refreshMutex.Unlock()
+
+	go func() {
+		if data, err := loader(key); err == nil {
+			cache.reloaded(key, item, data)
+		}
+		cache.refreshMutex.Lock()
+		delete(cache.refreshing, key)
+		cache.refreshMutex.Unlock()
+	}()
+}
+
+// reloaded stores the data reloaded by a refresh of item, keeping its fixed
+// deadline like the other updates of an item. The data is dropped if item left
+// the cache or was overwritten during the refresh, so that it does not bring
+// back a deleted key or clobber a newer write
+func (cache *Cache[K, V]) reloaded(key K, item *Item[V], data V) {
+	cache.mutex.Lock()
+	if cache.closed || cache.items[key] != item {
+		cache.mutex.Unlock()
+		return
+	}
+	data = cache.merged(key, data)
+	evictions := cache.insert(key, cache.renewed(key, item, data))
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+}
@@ -0,0 +1,181 @@
+package ttlcache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefreshAhead(t *testing.T) {
+	cache := NewCache(time.Hour)
+	defer cache.Close()
+	cache.SetSlidingExpiration(false)
+
+	var calls int32
+	release := make(chan struct{})
+	cache.RefreshAhead(150*time.Millisecond, func(key string) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "fresh", nil
+	})
+
+	cache.SetWithTTL("hello", "stale", 200*time.Millisecond)
+	if data, _ := cache.Get("hello"); data != "stale" {
+		t.Errorf("Expected `hello` to not be refreshed while far from expiry")
+	}
+	<-time.After(100 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if data, exists := cache.Get("hello"); !exists || data != "stale" {
+				t.Errorf("Expected Get to serve the stale value during the refresh")
+			}
+		}()
+	}
+	wg.Wait()
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if data, _ := cache.Peek("hello"); data == "fresh" {
+			break
+		}
+		<-time.After(5 * time.Millisecond)
+	}
+	if data, exists := cache.Peek("hello"); !exists || data != "fresh" {
+		t.Errorf("Expected `hello` to have been refreshed in the background")
+	}
+	if calls != 1 {
+		t.Errorf("Expected exactly one refresh, got %d", calls)
+	}
+	if remaining, _ := cache.TTLRemaining("hello"); remaining < 150*time.Millisecond {
+		t.Errorf("Expected the refreshed `hello` to have a fresh ttl, got %s", remaining)
+	}
+}
+
+func TestMaxConcurrentRefreshes(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Minute, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+	cache.SetSlidingExpiration(false)
+	cache.SetMaxConcurrentRefreshes(3)
+
+	var running, peak, calls int32
+	release := make(chan struct{})
+	cache.RefreshAhead(10*time.Second, func(key string) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		n := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&peak)
+			if n <= max || atomic.CompareAndSwapInt32(&peak, max, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&running, -1)
+		return "fresh", nil
+	})
+
+	for i := 0; i < 20; i++ {
+		cache.Set(fmt.Sprintf("key %d", i), "stale")
+	}
+	clock.Advance(55 * time.Second)
+	for i := 0; i < 20; i++ {
+		if data, _ := cache.Get(fmt.Sprintf("key %d", i)); data != "stale" {
+			t.Errorf("Expected the stale value to be served, got %q", data)
+		}
+	}
+	if skipped := cache.SkippedRefreshes(); skipped != 17 {
+		t.Errorf("Expected 17 refreshes to be skipped, got %d", skipped)
+	}
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&calls) < 3 && time.Now().Before(deadline) {
+		<-time.After(5 * time.Millisecond)
+	}
+	close(release)
+	for atomic.LoadInt32(&running) > 0 && time.Now().Before(deadline) {
+		<-time.After(5 * time.Millisecond)
+	}
+	if peak := atomic.LoadInt32(&peak); peak > 3 {
+		t.Errorf("Expected at most 3 refreshes in flight, got %d", peak)
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 3 {
+		t.Errorf("Expected only 3 refreshes to be started, got %d", calls)
+	}
+}
+
+func TestRefreshAheadKeepsDeadline(t *testing.T) {
+	cache := NewCache(time.Hour)
+	defer cache.Close()
+
+	refreshed := make(chan struct{})
+	cache.RefreshAhead(time.Minute, func(key string) (string, error) {
+		defer close(refreshed)
+		return "fresh", nil
+	})
+
+	deadline := time.Now().Add(30 * time.Second).Truncate(time.Second)
+	cache.SetWithDeadline("hello", "stale", deadline)
+	cache.Get("hello")
+	<-refreshed
+
+	end := time.Now().Add(time.Second)
+	for time.Now().Before(end) {
+		if data, _ := cache.Peek("hello"); data == "fresh" {
+			break
+		}
+		<-time.After(5 * time.Millisecond)
+	}
+	if data, _ := cache.Peek("hello"); data != "fresh" {
+		t.Fatalf("Expected `hello` to have been refreshed in the background")
+	}
+	if expiresAt, _ := cache.ExpiresAt("hello"); !expiresAt.Equal(deadline) {
+		t.Errorf("Expected the refresh to keep the deadline of `hello`, got %s", expiresAt)
+	}
+}
+
+func TestRefreshAheadDropsStaleReload(t *testing.T) {
+	cache := NewCache(time.Hour)
+	defer cache.Close()
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	cache.RefreshAhead(time.Minute, func(key string) (string, error) {
+		started <- struct{}{}
+		<-release
+		return "reloaded", nil
+	})
+
+	cache.SetWithTTL("deleted", "stale", 30*time.Second)
+	cache.SetWithTTL("written", "stale", 30*time.Second)
+	cache.Get("deleted")
+	cache.Get("written")
+	<-started
+	<-started
+	cache.Delete("deleted")
+	cache.SetWithTTL("written", "newer", time.Hour)
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		cache.refreshMutex.Lock()
+		pending := len(cache.refreshing)
+		cache.refreshMutex.Unlock()
+		if pending == 0 {
+			break
+		}
+		<-time.After(5 * time.Millisecond)
+	}
+	if data, found := cache.Get("deleted"); found {
+		t.Errorf("Expected a refresh to not bring back a deleted key, got %q", data)
+	}
+	if data, _ := cache.Get("written"); data != "newer" {
+		t.Errorf("Expected a refresh to not overwrite a newer write, got %q", data)
+	}
+}
@@ -0,0 +1,110 @@
+package ttlcache
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// ShardedCache spreads its items over independent caches to reduce lock contention
+// Keys are strings, which are hashed to pick the shard of an item
+type ShardedCache[V any] struct {
+	shards []*Cache[string, V]
+}
+
+// NewShardedCache is a helper to create instance of the ShardedCache struct holding strings
+func NewShardedCache(duration time.Duration, shards int) *ShardedCache[string] {
+	return NewSharded[string](duration, shards)
+}
+
+// NewSharded is a helper to create instance of the ShardedCache struct holding values of type V
+func NewSharded[V any](duration time.Duration, shards int) *ShardedCache[V] {
+	if shards < 1 {
+		shards = 1
+	}
+	cache := &ShardedCache[V]{shards: make([]*Cache[string, V], shards)}
+	for i := range cache.shards {
+		cache.shards[i] = New[string, V](duration)
+	}
+	return cache
+}
+
+// shard returns the cache holding key
+func (cache *ShardedCache[V]) shard(key string) *Cache[string, V] {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return cache.shards[hash.Sum32()%uint32(len(cache.shards))]
+}
+
+// Set is a thread-safe way to add new items to the map
+func (cache *ShardedCache[V]) Set(key string, data V) {
+	cache.shard(key).Set(key, data)
+}
+
+// Get is a thread-safe way to lookup items
+// Every lookup, also touches the item, hence extending it's life
+func (cache *ShardedCache[V]) Get(key string) (data V, found bool) {
+	return cache.shard(key).Get(key)
+}
+
+// Delete is a thread-safe way to delete an item
+func (cache *ShardedCache[V]) Delete(key string) {
+	cache.shard(key).Delete(key)
+}
+
+// Count returns the number of items in all shards
+func (cache *ShardedCache[V]) Count() int {
+	count := 0
+	for _, shard := range cache.shards {
+		count += shard.Count()
+	}
+	return count
+}
+
+// SizeBytes returns the total size of the values in all shards
+func (cache *ShardedCache[V]) SizeBytes() int64 {
+	var bytes int64
+	for _, shard := range cache.shards {
+		bytes += shard.SizeBytes()
+	}
+	return bytes
+}
+
+// Keys returns a snapshot of the keys of all live items in all shards
+func (cache *ShardedCache[V]) Keys() []string {
+	var keys []string
+	for _, shard := range cache.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	return keys
+}
+
+// Stats returns the sum of the counters of all shards
+func (cache *ShardedCache[V]) Stats() Stats {
+	var total Stats
+	for _, shard := range cache.shards {
+		stats := shard.Stats()
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Evictions += stats.Evictions
+		total.Expired += stats.Expired
+		// shards peak at different times, so the sum is an upper bound
+		total.PeakItems += stats.PeakItems
+		total.PeakBytes += stats.PeakBytes
+		total.SlowCleanups += stats.SlowCleanups
+	}
+	return total
+}
+
+// Flush deletes all items of all shards
+func (cache *ShardedCache[V]) Flush() {
+	for _, shard := range cache.shards {
+		shard.Flush()
+	}
+}
+
+// Close stops the cleanup goroutines of all shards
+func (cache *ShardedCache[V]) Close() {
+	for _, shard := range cache.shards {
+		shard.Close()
+	}
+}
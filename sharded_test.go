@@ -0,0 +1,78 @@
+package ttlcache
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShardedCache(t *testing.T) {
+	cache := NewShardedCache(time.Second, 8)
+	defer cache.Close()
+
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key %d", i), fmt.Sprintf("value %d", i))
+	}
+	if count := cache.Count(); count != 100 {
+		t.Errorf("Expected sharded cache to contain 100 items, got %d", count)
+	}
+
+	spread := 0
+	for _, shard := range cache.shards {
+		if shard.Count() > 0 {
+			spread++
+		}
+	}
+	if spread < 2 {
+		t.Errorf("Expected items to be spread over several shards")
+	}
+
+	data, exists := cache.Get("key 42")
+	if !exists || data != "value 42" {
+		t.Errorf("Expected sharded cache to return `value 42` for `key 42`")
+	}
+	cache.Get("missing")
+	cache.Delete("key 42")
+	if _, exists := cache.Get("key 42"); exists {
+		t.Errorf("Expected `key 42` to have been deleted")
+	}
+
+	keys := cache.Keys()
+	sort.Strings(keys)
+	if len(keys) != 99 {
+		t.Errorf("Expected 99 keys, got %d", len(keys))
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 2 || stats.Evictions != 1 {
+		t.Errorf("Expected summed stats of the shards, got %+v", stats)
+	}
+
+	cache.Flush()
+	if count := cache.Count(); count != 0 {
+		t.Errorf("Expected flushed sharded cache to be empty, got %d items", count)
+	}
+}
+
+func BenchmarkParallelSet(b *testing.B) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	benchmarkParallelSet(b, cache.Set)
+}
+
+func BenchmarkParallelShardedSet(b *testing.B) {
+	cache := NewShardedCache(time.Minute, 16)
+	defer cache.Close()
+	benchmarkParallelSet(b, cache.Set)
+}
+
+func benchmarkParallelSet(b *testing.B, set func(key string, data string)) {
+	var counter int64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := atomic.AddInt64(&counter, 1)
+			set(strconv.FormatInt(i%10000, 10), "value")
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package ttlcache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// Stats is a point-in-time copy of the cache counters
+type Stats struct {
+	// Hits counts lookups that found a live item
+	Hits uint64
+	// Misses counts lookups that found no live item
+	Misses uint64
+	// Evictions counts items removed before they expired
+	Evictions uint64
+	// Expired counts items removed because they outlived their ttl
+	Expired uint64
+	// PeakItems is the largest number of items the cache has held
+	PeakItems int
+	// PeakBytes is the largest total size of the values the cache has held
+	PeakBytes int64
+	// SlowCleanups counts sweeps that took longer than the slow cleanup threshold
+	SlowCleanups uint64
+}
+
+// stats holds the counters behind Stats, updated atomically outside the cache mutex
+type stats struct {
+	hits         uint64
+	misses       uint64
+	evictions    uint64
+	expired      uint64
+	peakItems    int64
+	peakBytes    int64
+	slowCleanups uint64
+}
+
+func (s *stats) lookup(found bool) {
+	if found {
+		atomic.AddUint64(&s.hits, 1)
+	} else {
+		atomic.AddUint64(&s.misses, 1)
+	}
+}
+
+// grown raises the high-watermarks to the current size of the cache,
+// it must be called with the cache mutex held
+func (s *stats) grown(items int, bytes int64) {
+	if int64(items) > atomic.LoadInt64(&s.peakItems) {
+		atomic.StoreInt64(&s.peakItems, int64(items))
+	}
+	if bytes > atomic.LoadInt64(&s.peakBytes) {
+		atomic.StoreInt64(&s.peakBytes, bytes)
+	}
+}
+
+func (s *stats) evicted(reason EvictionReason) {
+	switch reason {
+	case Expired:
+		atomic.AddUint64(&s.expired, 1)
+	case Replaced:
+	default:
+		atomic.AddUint64(&s.evictions, 1)
+	}
+}
+
+// Stats returns the hit, miss and eviction counters of the cache, along with
+// its high-watermarks
+func (cache *Cache[K, V]) Stats() Stats {
+	return Stats{
+		Hits:         atomic.LoadUint64(&cache.stats.hits),
+		Misses:       atomic.LoadUint64(&cache.stats.misses),
+		Evictions:    atomic.LoadUint64(&cache.stats.evictions),
+		Expired:      atomic.LoadUint64(&cache.stats.expired),
+		PeakItems:    int(atomic.LoadInt64(&cache.stats.peakItems)),
+		PeakBytes:    atomic.LoadInt64(&cache.stats.peakBytes),
+		SlowCleanups: atomic.LoadUint64(&cache.stats.slowCleanups),
+	}
+}
+
+// ResetStats sets all the counters of the cache back to zero
+// The high-watermarks start over from the size of the cache at the next store
+func (cache *Cache[K, V]) ResetStats() {
+	atomic.StoreUint64(&cache.stats.hits, 0)
+	atomic.StoreUint64(&cache.stats.misses, 0)
+	atomic.StoreUint64(&cache.stats.evictions, 0)
+	atomic.StoreUint64(&cache.stats.expired, 0)
+	atomic.StoreInt64(&cache.stats.peakItems, 0)
+	atomic.StoreInt64(&cache.stats.peakBytes, 0)
+	atomic.StoreUint64(&cache.stats.slowCleanups, 0)
+	cache.missed.clear()
+}
+
+// missTracker counts the misses of the most recently missed keys
+type missTracker[K comparable] struct {
+	sync.Mutex
+	max      int
+	order    list.List
+	elements map[K]*list.Element
+}
+
+// missCount is the value of the elements of a missTracker
+type missCount[K comparable] struct {
+	key   K
+	count uint64
+}
+
+// SetMissTracking makes the cache count the misses of every key, for KeyStats,
+// remembering up to max keys: once more keys have missed, the least recently
+// missed key is forgotten. Keys are counted whether or not they are stored later
+// Lowering max forgets the least recently missed keys beyond it, and ResetStats
+// forgets all counts. A max of 0 disables miss tracking, which is the default
+func (cache *Cache[K, V]) SetMissTracking(max int) {
+	cache.mutex.Lock()
+	cache.trackMisses = max > 0
+	cache.mutex.Unlock()
+	cache.missed.resize(max)
+}
+
+// KeyStats returns the number of lookups that found the live item of key, since it
+// was stored, and the number of lookups that missed key, if SetMissTracking is in
+// effect and the key is among the tracked ones. ok is false if key has neither
+// a live item nor tracked misses
+func (cache *Cache[K, V]) KeyStats(key K) (hits, misses uint64, ok bool) {
+	key = cache.normalize(key)
+	cache.mutex.RLock()
+	if item, exists := cache.items[key]; exists && !cache.expired(item) {
+		hits = atomic.LoadUint64(&item.accesses)
+		ok = true
+	}
+	cache.mutex.RUnlock()
+	cache.missed.Lock()
+	if element, exists := cache.missed.elements[key]; exists {
+		misses = element.Value.(*missCount[K]).count
+		ok = true
+	}
+	cache.missed.Unlock()
+	return
+}
+
+// miss counts a miss of key, evicting the least recently missed key if needed
+func (tracker *missTracker[K]) miss(key K) {
+	tracker.Lock()
+	defer tracker.Unlock()
+	if tracker.max <= 0 {
+		return
+	}
+	if element, exists := tracker.elements[key]; exists {
+		element.Value.(*missCount[K]).count++
+		tracker.order.MoveToFront(element)
+		return
+	}
+	if tracker.elements == nil {
+		tracker.elements = map[K]*list.Element{}
+	}
+	tracker.elements[key] = tracker.order.PushFront(&missCount[K]{key: key, count: 1})
+	tracker.trim()
+}
+
+// resize sets the number of tracked keys, forgetting the least recently missed ones
+func (tracker *missTracker[K]) resize(max int) {
+	tracker.Lock()
+	if max < 0 {
+		max = 0
+	}
+	tracker.max = max
+	tracker.trim()
+	tracker.Unlock()
+}
+
+// clear forgets the counts of all keys
+func (tracker *missTracker[K]) clear() {
+	tracker.Lock()
+	tracker.order.Init()
+	tracker.elements = nil
+	tracker.Unlock()
+}
+
+// trim forgets the least recently missed keys beyond max,
+// it must be called with the tracker mutex held
+func (tracker *missTracker[K]) trim() {
+	for tracker.order.Len() > tracker.max {
+		element := tracker.order.Back()
+		tracker.order.Remove(element)
+		delete(tracker.elements, element.Value.(*missCount[K]).key)
+	}
+}
@@ -0,0 +1,153 @@
+package ttlcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.SetWithTTL("hello", "world", time.Minute)
+	cache.SetWithTTL("short", "lived", 100*time.Millisecond)
+	cache.SetWithTTL("doomed", "value", time.Minute)
+	cache.SetWithTTL("hello", "there", time.Minute)
+
+	cache.Get("hello")
+	cache.Get("hello")
+	cache.Get("missing")
+	cache.Delete("doomed")
+	cache.Delete("missing")
+
+	<-time.After(1500 * time.Millisecond)
+	cache.Get("short")
+
+	expected := Stats{Hits: 2, Misses: 2, Evictions: 1, Expired: 1, PeakItems: 3, PeakBytes: 15}
+	if stats := cache.Stats(); stats != expected {
+		t.Errorf("Expected stats %+v, got %+v", expected, stats)
+	}
+
+	cache.ResetStats()
+	if stats := cache.Stats(); stats != (Stats{}) {
+		t.Errorf("Expected stats to be reset, got %+v", stats)
+	}
+}
+
+func TestPeakStats(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.Set("a", "1")
+	cache.Set("b", "22")
+	cache.Set("c", "333")
+	cache.Delete("b")
+	cache.Delete("c")
+	cache.Set("a", "4444")
+
+	stats := cache.Stats()
+	if stats.PeakItems != 3 || stats.PeakBytes != 6 {
+		t.Errorf("Expected peaks of 3 items and 6 bytes, got %d items and %d bytes", stats.PeakItems, stats.PeakBytes)
+	}
+	if count := cache.Count(); count != 1 {
+		t.Errorf("Expected the cache to have shrunk to 1 item, got %d", count)
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	if items, bytes := cache.Capacity(); items != 0 || bytes != 0 {
+		t.Errorf("Expected an unbounded cache, got %d items and %d bytes", items, bytes)
+	}
+	cache.SetMaxItems(100)
+	cache.SetMaxBytes(1 << 20)
+	if items, bytes := cache.Capacity(); items != 100 || bytes != 1<<20 {
+		t.Errorf("Expected caps of 100 items and 1MiB, got %d items and %d bytes", items, bytes)
+	}
+}
+
+func TestSlowCleanups(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	var swept []int
+	cache.OnSlowCleanup = func(duration time.Duration, items int) {
+		if duration <= 0 {
+			t.Errorf("Expected the duration of the sweep, got %v", duration)
+		}
+		swept = append(swept, items)
+	}
+	for i := 0; i < 1000; i++ {
+		cache.SetWithTTL(fmt.Sprintf("key %d", i), "value", time.Nanosecond)
+	}
+	cache.Cleanup()
+	if stats := cache.Stats(); stats.SlowCleanups != 0 || len(swept) != 0 {
+		t.Errorf("Expected no slow sweeps without a threshold")
+	}
+
+	cache.SetSlowCleanupThreshold(time.Nanosecond)
+	for i := 0; i < 1000; i++ {
+		cache.SetWithTTL(fmt.Sprintf("key %d", i), "value", time.Nanosecond)
+	}
+	cache.Cleanup()
+	if stats := cache.Stats(); stats.SlowCleanups != 1 {
+		t.Errorf("Expected a slow sweep to be counted, got %d", stats.SlowCleanups)
+	}
+	if len(swept) != 1 || swept[0] != 1000 {
+		t.Errorf("Expected OnSlowCleanup to report a sweep of 1000 items, got %v", swept)
+	}
+
+	cache.SetSlowCleanupThreshold(time.Hour)
+	cache.Cleanup()
+	if stats := cache.Stats(); stats.SlowCleanups != 1 {
+		t.Errorf("Expected a fast sweep to not be counted, got %d", stats.SlowCleanups)
+	}
+}
+
+func TestKeyStats(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+
+	cache.Set("hot", "value")
+	for i := 0; i < 5; i++ {
+		cache.Get("hot")
+	}
+	cache.GetMany([]string{"hot", "absent"})
+	if hits, misses, ok := cache.KeyStats("hot"); !ok || hits != 6 || misses != 0 {
+		t.Errorf("Expected 6 hits on `hot`, got %d hits, %d misses, %v", hits, misses, ok)
+	}
+	if _, _, ok := cache.KeyStats("absent"); ok {
+		t.Errorf("Expected misses to not be tracked by default")
+	}
+
+	cache.SetMissTracking(2)
+	for i := 0; i < 3; i++ {
+		cache.Get("absent")
+	}
+	cache.GetMany([]string{"absent", "hot"})
+	if hits, misses, ok := cache.KeyStats("absent"); !ok || hits != 0 || misses != 4 {
+		t.Errorf("Expected 4 misses on `absent`, got %d hits, %d misses, %v", hits, misses, ok)
+	}
+	cache.Get("other")
+	cache.Get("another")
+	if _, _, ok := cache.KeyStats("absent"); ok {
+		t.Errorf("Expected the least recently missed key to be forgotten")
+	}
+	if _, misses, _ := cache.KeyStats("another"); misses != 1 {
+		t.Errorf("Expected `another` to be tracked, got %d misses", misses)
+	}
+
+	cache.ResetStats()
+	if _, _, ok := cache.KeyStats("another"); ok {
+		t.Errorf("Expected ResetStats to forget the misses")
+	}
+	cache.SetMissTracking(0)
+	cache.Get("absent")
+	if _, _, ok := cache.KeyStats("absent"); ok {
+		t.Errorf("Expected misses to no longer be tracked")
+	}
+}
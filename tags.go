@@ -0,0 +1,59 @@
+package ttlcache
+
+// SetWithTags is a thread-safe way to add an item carrying tags, so that it can be
+// deleted along with the other items sharing one of them by InvalidateTag
+// The tags last until the item leaves the cache or is set again
+func (cache *Cache[K, V]) SetWithTags(key K, data V, tags ...string) {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return
+	}
+	item := cache.newItem(data, cache.ttlFor(key, data, 0))
+	item.tags = append([]string(nil), tags...)
+	evictions := cache.insert(key, item)
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+}
+
+// InvalidateTag is a thread-safe way to delete every item carrying tag,
+// returning the number of items deleted
+// It only visits the items carrying tag, whatever the size of the cache
+func (cache *Cache[K, V]) InvalidateTag(tag string) int {
+	cache.mutex.Lock()
+	var evictions []EvictionEvent[K, V]
+	for key := range cache.tags[tag] {
+		evictions = cache.remove(evictions, key, cache.items[key], Deleted)
+	}
+	onEvicted := cache.OnEvicted
+	cache.mutex.Unlock()
+	cache.notify(onEvicted, evictions)
+	return len(evictions)
+}
+
+// tag records the tags of an item in the reverse index,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) tag(key K, item *Item[V]) {
+	for _, tag := range item.tags {
+		if cache.tags == nil {
+			cache.tags = map[string]map[K]struct{}{}
+		}
+		if cache.tags[tag] == nil {
+			cache.tags[tag] = map[K]struct{}{}
+		}
+		cache.tags[tag][key] = struct{}{}
+	}
+}
+
+// untag forgets the tags of an item leaving the cache,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) untag(key K, item *Item[V]) {
+	for _, tag := range item.tags {
+		delete(cache.tags[tag], key)
+		if len(cache.tags[tag]) == 0 {
+			delete(cache.tags, tag)
+		}
+	}
+}
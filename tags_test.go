@@ -0,0 +1,63 @@
+package ttlcache
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cache := NewWithConfig[string, string](Config{TTL: time.Second, Clock: clock})
+	defer cache.Close()
+	cache.SetCleanupInterval(time.Hour)
+
+	cache.SetWithTags("a", "value", "red")
+	cache.SetWithTags("b", "value", "red", "blue")
+	cache.SetWithTags("c", "value", "blue")
+	cache.Set("d", "value")
+
+	if removed := cache.InvalidateTag("red"); removed != 2 {
+		t.Errorf("Expected 2 items tagged red to be deleted, %d removed", removed)
+	}
+	keys := cache.Keys()
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "c,d" {
+		t.Errorf("Expected exactly the tagged keys to be deleted, left %v", keys)
+	}
+	if _, exists := cache.tags["red"]; exists || len(cache.tags["blue"]) != 1 {
+		t.Errorf("Expected the index to forget the deleted keys, got %v", cache.tags)
+	}
+	if removed := cache.InvalidateTag("red"); removed != 0 {
+		t.Errorf("Expected an unused tag to delete nothing, %d removed", removed)
+	}
+
+	cache.Set("c", "untagged")
+	if removed := cache.InvalidateTag("blue"); removed != 0 || !cache.Has("c") {
+		t.Errorf("Expected setting an item again to drop its tags")
+	}
+
+	cache.SetWithTags("e", "value", "green")
+	clock.Advance(2 * time.Second)
+	cache.Cleanup()
+	if len(cache.tags) != 0 {
+		t.Errorf("Expected expired items to leave the index, got %v", cache.tags)
+	}
+}
+
+func TestTagsKeptByUpdates(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+
+	cache.SetWithTags("replaced", "1", "group")
+	cache.SetWithTags("swapped", "1", "group")
+	cache.Replace("replaced", "2")
+	cache.CompareAndSwap("swapped", "1", "2")
+	if invalidated := cache.InvalidateTag("group"); invalidated != 2 {
+		t.Errorf("Expected Replace and CompareAndSwap to keep the tags, %d invalidated", invalidated)
+	}
+	if cache.Has("replaced") || cache.Has("swapped") {
+		t.Errorf("Expected the updated items to be invalidated")
+	}
+}
@@ -0,0 +1,27 @@
+package ttlcache
+
+import "time"
+
+// IntCache is a cache of integers, which Increment and Decrement update in place
+type IntCache = Cache[string, int64]
+
+// BytesCache is a cache of byte slices, see SetCopyOnStore and SetCopyOnGet to stop sharing them with callers
+type BytesCache = Cache[string, []byte]
+
+// TimeCache is a cache of points in time
+type TimeCache = Cache[string, time.Time]
+
+// NewIntCache is a helper to create instance of the Cache struct holding integers
+func NewIntCache(duration time.Duration) *IntCache {
+	return New[string, int64](duration)
+}
+
+// NewBytesCache is a helper to create instance of the Cache struct holding byte slices
+func NewBytesCache(duration time.Duration) *BytesCache {
+	return New[string, []byte](duration)
+}
+
+// NewTimeCache is a helper to create instance of the Cache struct holding points in time
+func NewTimeCache(duration time.Duration) *TimeCache {
+	return New[string, time.Time](duration)
+}
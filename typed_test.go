@@ -0,0 +1,68 @@
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIntCache(t *testing.T) {
+	cache := NewIntCache(time.Second)
+	defer cache.Close()
+
+	cache.Set("counter", 40)
+	if n, err := cache.Increment("counter", 2); err != nil || n != 42 {
+		t.Errorf("Expected counter to be incremented to 42, got %d (%v)", n, err)
+	}
+	if data, exists := cache.Get("counter"); !exists || data != 42 {
+		t.Errorf("Expected cache to return 42 for `counter`, got %d", data)
+	}
+	cache.SetWithTTL("short", 1, 10*time.Millisecond)
+	<-time.After(50 * time.Millisecond)
+	if _, exists := cache.Get("short"); exists {
+		t.Errorf("Expected `short` to have expired")
+	}
+	cache.Delete("counter")
+	if count := cache.Count(); count > 1 {
+		t.Errorf("Expected `counter` to have been deleted, got %d items", count)
+	}
+}
+
+func TestBytesCache(t *testing.T) {
+	cache := NewBytesCache(time.Second)
+	defer cache.Close()
+	cache.SetCopyOnStore(true)
+
+	data := []byte("world")
+	cache.Set("hello", data)
+	data[0] = 'W'
+	if stored, exists := cache.Get("hello"); !exists || string(stored) != "world" {
+		t.Errorf("Expected cache to return `world` for `hello`, got %q", stored)
+	}
+	if size := cache.SizeBytes(); size != 5 {
+		t.Errorf("Expected slices to be sized by their length, got %d", size)
+	}
+	cache.SetWithTTL("short", []byte("x"), 10*time.Millisecond)
+	<-time.After(50 * time.Millisecond)
+	if _, exists := cache.Get("short"); exists {
+		t.Errorf("Expected `short` to have expired")
+	}
+}
+
+func TestTimeCache(t *testing.T) {
+	cache := NewTimeCache(time.Second)
+	defer cache.Close()
+
+	now := time.Now()
+	cache.Set("seen", now)
+	if data, exists := cache.Get("seen"); !exists || !data.Equal(now) {
+		t.Errorf("Expected cache to return %v for `seen`, got %v", now, data)
+	}
+	if _, err := cache.Increment("seen", 1); err != ErrNotInteger {
+		t.Errorf("Expected incrementing a time to return ErrNotInteger, got %v", err)
+	}
+	cache.SetWithTTL("short", now, 10*time.Millisecond)
+	<-time.After(50 * time.Millisecond)
+	if data, exists := cache.Get("short"); exists || !data.IsZero() {
+		t.Errorf("Expected `short` to have expired and return the zero time")
+	}
+}
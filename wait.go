@@ -0,0 +1,111 @@
+package ttlcache
+
+import "time"
+
+// closedChan is returned to waiters on keys that are already gone
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// WaitExpired returns a channel that is closed once the item for key leaves the
+// cache, by expiring, being deleted or being evicted, but not by being replaced
+// If there is no live item for key, the returned channel is already closed.
+// Closing the cache closes every pending channel
+func (cache *Cache[K, V]) WaitExpired(key K) <-chan struct{} {
+	key = cache.normalize(key)
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	item, exists := cache.items[key]
+	if cache.closed || !exists || cache.expired(item) {
+		return closedChan
+	}
+	ch := make(chan struct{})
+	if cache.waiters == nil {
+		cache.waiters = map[K][]chan struct{}{}
+	}
+	cache.waiters[key] = append(cache.waiters[key], ch)
+	return ch
+}
+
+// release closes the channels waiting on key,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) release(key K) {
+	for _, ch := range cache.waiters[key] {
+		close(ch)
+	}
+	delete(cache.waiters, key)
+}
+
+// releaseAll closes every channel waiting on a key,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) releaseAll() {
+	for key := range cache.waiters {
+		cache.release(key)
+	}
+}
+
+// GetWait is a thread-safe way to lookup an item, waiting up to timeout for it
+// to be set if it is missing. It returns false if no item was set in time or
+// the cache was closed while waiting
+func (cache *Cache[K, V]) GetWait(key K, timeout time.Duration) (data V, found bool) {
+	key = cache.normalize(key)
+	if data, found = cache.Get(key); found {
+		return
+	}
+	cache.mutex.Lock()
+	if cache.closed {
+		cache.mutex.Unlock()
+		return
+	}
+	if item, exists := cache.items[key]; exists && !cache.expired(item) {
+		// the item was set since the lookup above
+		cache.mutex.Unlock()
+		return cache.Get(key)
+	}
+	ch := make(chan struct{})
+	if cache.arrivals == nil {
+		cache.arrivals = map[K][]chan struct{}{}
+	}
+	cache.arrivals[key] = append(cache.arrivals[key], ch)
+	cache.mutex.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-ch:
+		return cache.Get(key)
+	case <-timer.C:
+		cache.mutex.Lock()
+		cache.forget(key, ch)
+		cache.mutex.Unlock()
+		return
+	}
+}
+
+// arrive closes the channels of the GetWait calls waiting on key,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) arrive(key K) {
+	for _, ch := range cache.arrivals[key] {
+		close(ch)
+	}
+	delete(cache.arrivals, key)
+}
+
+// forget drops the channel of a GetWait call that timed out,
+// it must be called with the cache mutex held
+func (cache *Cache[K, V]) forget(key K, ch chan struct{}) {
+	pending := cache.arrivals[key]
+	for i, other := range pending {
+		if other == ch {
+			pending = append(pending[:i], pending[i+1:]...)
+			break
+		}
+	}
+	if len(pending) == 0 {
+		delete(cache.arrivals, key)
+	} else {
+		cache.arrivals[key] = pending
+	}
+}
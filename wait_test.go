@@ -0,0 +1,87 @@
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaitExpired(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+	cache.SetCleanupInterval(10 * time.Millisecond)
+
+	begin := time.Now()
+	cache.SetWithTTL("lock", "held", 100*time.Millisecond)
+	select {
+	case <-cache.WaitExpired("lock"):
+		if elapsed := time.Since(begin); elapsed < 100*time.Millisecond || elapsed > 500*time.Millisecond {
+			t.Errorf("Expected the wait to unblock near the deadline, took %s", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected the wait to unblock once `lock` expired")
+	}
+
+	select {
+	case <-cache.WaitExpired("missing"):
+	default:
+		t.Errorf("Expected waiting on a missing key to return a closed channel")
+	}
+
+	cache.Set("deleted", "value")
+	wait := cache.WaitExpired("deleted")
+	cache.Set("deleted", "replaced")
+	select {
+	case <-wait:
+		t.Errorf("Expected replacing `deleted` to not unblock the wait")
+	default:
+	}
+	cache.Delete("deleted")
+	select {
+	case <-wait:
+	default:
+		t.Errorf("Expected deleting `deleted` to unblock the wait")
+	}
+}
+
+func TestGetWait(t *testing.T) {
+	cache := NewCache(time.Second)
+	defer cache.Close()
+
+	cache.Set("ready", "now")
+	if data, found := cache.GetWait("ready", time.Millisecond); !found || data != "now" {
+		t.Errorf("Expected a live item to be returned immediately")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cache.Set("hello", "world")
+	}()
+	begin := time.Now()
+	if data, found := cache.GetWait("hello", time.Second); !found || data != "world" {
+		t.Errorf("Expected the waiter to receive `world` for `hello`")
+	}
+	if elapsed := time.Since(begin); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the waiter to wake up once `hello` was set, took %s", elapsed)
+	}
+
+	if _, found := cache.GetWait("missing", 20*time.Millisecond); found {
+		t.Errorf("Expected the wait for `missing` to time out")
+	}
+	cache.mutex.RLock()
+	pending := len(cache.arrivals)
+	cache.mutex.RUnlock()
+	if pending != 0 {
+		t.Errorf("Expected timed out waiters to be forgotten, %d pending", pending)
+	}
+}
+
+func TestGetWaitClose(t *testing.T) {
+	cache := NewCache(time.Second)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cache.Close()
+	}()
+	if _, found := cache.GetWait("hello", time.Second); found {
+		t.Errorf("Expected closing the cache to end the wait without an item")
+	}
+}